@@ -4,6 +4,9 @@ if [ $# -eq 0 ]; then
     sunbeam query -n '{
         title: "Bitwarden Vault",
         description: "Search your Bitwarden passwords",
+        requirements: [
+            { name: "bw", link: "https://bitwarden.com/help/cli/" }
+        ],
         preferences: [
             {
                 name: "session",
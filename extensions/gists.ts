@@ -152,6 +152,7 @@ async function run(payload: sunbeam.Payload) {
                             key: "d",
                             type: "run",
                             command: "delete",
+                            confirm: "Delete {{item.title}}?",
                             reload: true,
                             params: {
                                 id: gist.id
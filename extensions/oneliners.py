@@ -191,6 +191,7 @@ elif payload['command'] == 'manage':
                     'key': 'd',
                     'type': 'run',
                     "reload": True,
+                    "confirm": True,
                     "command": "delete",
                     "params": {
                         "index": idx
@@ -5,6 +5,9 @@ if (Deno.args.length == 0) {
     const manifest: sunbeam.Manifest = {
         title: "Tailscale",
         description: "Manage your tailscale devices",
+        requirements: [
+            { name: "tailscale", link: "https://tailscale.com/download" }
+        ],
         root: ["list-devices"],
         commands: [
             {
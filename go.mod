@@ -55,5 +55,6 @@ require (
 
 require (
 	github.com/mattn/go-isatty v0.0.20
-	golang.org/x/sys v0.14.0 // indirect
+	golang.org/x/crypto v0.15.0
+	golang.org/x/sys v0.14.0
 )
@@ -0,0 +1,111 @@
+// Package catalog reads the index of the extensions published on the sunbeam website.
+package catalog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultURL is the catalog generated by scripts/build-catalog.ts.
+const DefaultURL = "https://pomdtr.github.io/sunbeam/catalog.json"
+
+type Catalog struct {
+	Extensions []Entry `json:"extensions"`
+}
+
+type Entry struct {
+	Name        string       `json:"name"`
+	Origin      string       `json:"origin"`
+	Title       string       `json:"title"`
+	Description string       `json:"description,omitempty"`
+	Version     string       `json:"version,omitempty"`
+	Preferences []Preference `json:"preferences,omitempty"`
+	Commands    []Command    `json:"commands,omitempty"`
+}
+
+type Preference struct {
+	Name  string `json:"name"`
+	Title string `json:"title"`
+}
+
+type Command struct {
+	Name  string `json:"name"`
+	Title string `json:"title"`
+}
+
+// Load reads a catalog from an url or a local file.
+func Load(source string) (Catalog, error) {
+	var catalogBytes []byte
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		resp, err := http.Get(source)
+		if err != nil {
+			return Catalog{}, fmt.Errorf("failed to download catalog: %w", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != 200 {
+			return Catalog{}, fmt.Errorf("failed to download catalog: %s", resp.Status)
+		}
+
+		b, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return Catalog{}, fmt.Errorf("failed to download catalog: %w", err)
+		}
+		catalogBytes = b
+	} else {
+		if strings.HasPrefix(source, "~/") {
+			source = filepath.Join(os.Getenv("HOME"), source[2:])
+		}
+
+		b, err := os.ReadFile(source)
+		if err != nil {
+			return Catalog{}, fmt.Errorf("failed to read catalog: %w", err)
+		}
+		catalogBytes = b
+	}
+
+	var catalog Catalog
+	if err := json.Unmarshal(catalogBytes, &catalog); err != nil {
+		return Catalog{}, fmt.Errorf("failed to decode catalog: %w", err)
+	}
+
+	return catalog, nil
+}
+
+// Search returns the entries matching all the words of the query, in their name, title, description or commands.
+func (c Catalog) Search(query string) []Entry {
+	words := strings.Fields(strings.ToLower(query))
+
+	var entries []Entry
+	for _, entry := range c.Extensions {
+		text := entry.searchText()
+
+		matches := true
+		for _, word := range words {
+			if !strings.Contains(text, word) {
+				matches = false
+				break
+			}
+		}
+
+		if matches {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries
+}
+
+func (e Entry) searchText() string {
+	fields := []string{e.Name, e.Title, e.Description}
+	for _, command := range e.Commands {
+		fields = append(fields, command.Name, command.Title)
+	}
+
+	return strings.ToLower(strings.Join(fields, "\n"))
+}
@@ -26,6 +26,10 @@ func NewCmdCustom(alias string, extension extensions.Extension, extensionConfig
 		Args:    cobra.NoArgs,
 		GroupID: CommandGroupExtension,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if !extension.IsSupported() {
+				return fmt.Errorf("extension %s is not supported on %s", alias, extensions.CurrentPlatform())
+			}
+
 			var inputBytes []byte
 			if !isatty.IsTerminal(os.Stdin.Fd()) {
 				b, err := io.ReadAll(os.Stdin)
@@ -73,12 +77,21 @@ func NewCmdCustom(alias string, extension extensions.Extension, extensionConfig
 	rootCmd.CompletionOptions.DisableDefaultCmd = true
 	rootCmd.SetHelpCommand(&cobra.Command{Hidden: true})
 
+	if !extension.IsSupported() {
+		rootCmd.Hidden = true
+		return rootCmd, nil
+	}
+
 	commands := extension.Manifest.Commands
 	sort.Slice(extension.Manifest.Commands, func(i, j int) bool {
 		return commands[i].Name < commands[j].Name
 	})
 
 	for _, command := range commands {
+		if !extension.SupportsCommand(command) {
+			continue
+		}
+
 		parts := strings.Split(command.Name, ".")
 
 		parentCmd := rootCmd
@@ -199,6 +212,16 @@ func runExtension(extension extensions.Extension, input types.Payload, interacti
 		return fmt.Errorf("command %s not found", input.Command)
 	}
 
+	if !interactive && extension.Type == extensions.ExtensionTypeHttp {
+		output, err := extension.Output(input)
+		if err != nil {
+			return err
+		}
+
+		_, err = os.Stdout.Write(output)
+		return err
+	}
+
 	if !interactive {
 		cmd, err := extension.Cmd(input)
 		if err != nil {
@@ -1,22 +1,30 @@
 package cli
 
 import (
+	"bufio"
+	"context"
 	_ "embed"
 	"fmt"
 	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/cli/go-gh/v2/pkg/tableprinter"
 	"github.com/mattn/go-isatty"
+	"github.com/pomdtr/sunbeam/internal/catalog"
 	"github.com/pomdtr/sunbeam/internal/config"
 	"github.com/pomdtr/sunbeam/internal/extensions"
 	"github.com/pomdtr/sunbeam/internal/github"
+	"github.com/pomdtr/sunbeam/internal/history"
+	"github.com/pomdtr/sunbeam/internal/sandbox"
+	"github.com/pomdtr/sunbeam/internal/secrets"
 	"github.com/pomdtr/sunbeam/internal/tui"
 	"github.com/pomdtr/sunbeam/internal/types"
 	"github.com/pomdtr/sunbeam/internal/utils"
+	"github.com/pomdtr/sunbeam/internal/watcher"
 	"github.com/spf13/cobra"
 	"golang.org/x/term"
 )
@@ -29,6 +37,8 @@ func NewCmdExtension(cfg config.Config) *cobra.Command {
 	}
 
 	cmd.AddCommand(NewCmdExtensionInstall(cfg))
+	cmd.AddCommand(NewCmdExtensionSearch(cfg))
+	cmd.AddCommand(NewCmdExtensionBrowse(cfg))
 	cmd.AddCommand(NewCmdExtensionUpgrade(cfg))
 	cmd.AddCommand(NewCmdExtensionRename(cfg))
 	cmd.AddCommand(NewCmdExtensionList(cfg))
@@ -36,6 +46,9 @@ func NewCmdExtension(cfg config.Config) *cobra.Command {
 	cmd.AddCommand(NewCmdExtensionConfigure(cfg))
 	cmd.AddCommand(NewCmdExtensionPublish())
 	cmd.AddCommand(NewCmdExtensionCreate())
+	cmd.AddCommand(NewCmdExtensionTest())
+	cmd.AddCommand(NewCmdExtensionDev())
+	cmd.AddCommand(NewCmdExtensionStorage(cfg))
 
 	return cmd
 }
@@ -47,12 +60,17 @@ func extractAlias(origin string) (string, error) {
 	}
 
 	base := filepath.Base(originUrl.Path)
+	for _, ext := range []string{".tar.gz", ".tgz", ".zip", ".git"} {
+		if strings.HasSuffix(base, ext) {
+			return strings.TrimSuffix(base, ext), nil
+		}
+	}
 
 	return strings.TrimSuffix(base, filepath.Ext(base)), nil
 }
 
 func normalizeOrigin(origin string) (string, error) {
-	if strings.HasPrefix(origin, "http://") || strings.HasPrefix(origin, "https://") {
+	if extensions.IsRemote(origin) {
 		return origin, nil
 	}
 
@@ -151,10 +169,14 @@ func NewCmdExtensionCreate() *cobra.Command {
 	return cmd
 }
 
+type installFlags struct {
+	Alias       string
+	Yes         bool
+	Preferences preferencesFlags
+}
+
 func NewCmdExtensionInstall(cfg config.Config) *cobra.Command {
-	var flags struct {
-		Alias string
-	}
+	var flags installFlags
 
 	cmd := &cobra.Command{
 		Use:     "install <origin>",
@@ -167,42 +189,180 @@ func NewCmdExtensionInstall(cfg config.Config) *cobra.Command {
 				return fmt.Errorf("failed to normalize origin: %w", err)
 			}
 
-			var alias string
-			if flags.Alias != "" {
-				alias = flags.Alias
-			} else {
-				a, err := extractAlias(origin)
-				if err != nil {
-					return fmt.Errorf("failed to get alias: %w", err)
-				}
-				alias = a
+			return installExtension(cmd, cfg, origin, flags)
+		},
+	}
+
+	cmd.Flags().StringVar(&flags.Alias, "alias", "", "alias for extension")
+	cmd.Flags().BoolVarP(&flags.Yes, "yes", "y", false, "approve the permissions requested by the extension")
+	addPreferencesFlags(cmd, &flags.Preferences)
+
+	return cmd
+
+}
+
+func installExtension(cmd *cobra.Command, cfg config.Config, origin string, flags installFlags) error {
+	var alias string
+	if flags.Alias != "" {
+		alias = flags.Alias
+	} else {
+		a, err := extractAlias(origin)
+		if err != nil {
+			return fmt.Errorf("failed to get alias: %w", err)
+		}
+		alias = a
+	}
+
+	if err := extensions.ValidateAlias(alias); err != nil {
+		return err
+	}
+
+	if _, ok := cfg.Extensions[alias]; ok {
+		return fmt.Errorf("extension %s already exists", alias)
+	}
+
+	extension, err := extensions.Install(origin)
+	if err != nil {
+		return fmt.Errorf("failed to load extension: %w", err)
+	}
+
+	if !extension.IsSupported() {
+		return fmt.Errorf("extension is not supported on %s", extensions.CurrentPlatform())
+	}
+
+	if err := extension.CheckRequirements(); err != nil {
+		return err
+	}
+
+	extensionConfig := config.ExtensionConfig{
+		Origin: origin,
+	}
+
+	if permissions := extension.Manifest.Permissions; permissions != nil {
+		if err := approvePermissions(cmd, alias, *permissions, flags.Yes); err != nil {
+			return err
+		}
+		extensionConfig.Permissions = permissions
+	}
+
+	if flags.Preferences.changed() {
+		preferences, err := updatePreferences(alias, extension.Manifest.Preferences, nil, flags.Preferences)
+		if err != nil {
+			return err
+		}
+		extensionConfig.Preferences = preferences
+	}
+
+	cfg.Extensions[alias] = extensionConfig
+
+	if err := cfg.Save(); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+
+	cmd.Printf("✅ Installed %s\n", alias)
+	return nil
+}
+
+func NewCmdExtensionSearch(cfg config.Config) *cobra.Command {
+	var flags struct {
+		Catalog string
+		Install bool
+		installFlags
+	}
+
+	cmd := &cobra.Command{
+		Use:   "search [query]",
+		Short: "Search the extension catalog",
+		Args:  cobra.ArbitraryArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cat, err := catalog.Load(catalogSource(cfg, flags.Catalog))
+			if err != nil {
+				return err
 			}
 
-			if _, err := extensions.LoadExtension(origin); err != nil {
-				return fmt.Errorf("failed to load extension: %w", err)
+			entries := cat.Search(strings.Join(args, " "))
+			if flags.Install {
+				if len(entries) != 1 {
+					return fmt.Errorf("%d extensions match the query, refine it to install one", len(entries))
+				}
+
+				if flags.Alias == "" {
+					flags.Alias = entries[0].Name
+				}
+
+				return installExtension(cmd, cfg, entries[0].Origin, flags.installFlags)
 			}
 
-			if _, ok := cfg.Extensions[alias]; ok {
-				return fmt.Errorf("extension %s already exists", alias)
+			if len(entries) == 0 {
+				return fmt.Errorf("no extension matches the query")
 			}
 
-			cfg.Extensions[alias] = config.ExtensionConfig{
-				Origin: origin,
+			var t tableprinter.TablePrinter
+			if isatty.IsTerminal(os.Stdout.Fd()) {
+				w, _, err := term.GetSize(int(os.Stdout.Fd()))
+				if err != nil {
+					return err
+				}
+				t = tableprinter.New(os.Stdout, true, w)
+			} else {
+				t = tableprinter.New(os.Stdout, false, 0)
 			}
 
-			if err := cfg.Save(); err != nil {
-				return fmt.Errorf("failed to save config: %w", err)
+			for _, entry := range entries {
+				var commands []string
+				for _, command := range entry.Commands {
+					commands = append(commands, command.Name)
+				}
+
+				t.AddField(entry.Name)
+				t.AddField(entry.Title)
+				t.AddField(entry.Description)
+				t.AddField(strings.Join(commands, ", "))
+				t.EndRow()
 			}
 
-			cmd.Printf("✅ Installed %s\n", alias)
-			return nil
+			return t.Render()
 		},
 	}
 
-	cmd.Flags().StringVar(&flags.Alias, "alias", "", "alias for extension")
+	cmd.Flags().StringVar(&flags.Catalog, "catalog", "", "url or path of the catalog")
+	cmd.Flags().BoolVar(&flags.Install, "install", false, "install the matching extension")
+	cmd.Flags().StringVar(&flags.Alias, "alias", "", "alias for the installed extension")
+	cmd.Flags().BoolVarP(&flags.Yes, "yes", "y", false, "approve the permissions requested by the installed extension")
+	addPreferencesFlags(cmd, &flags.Preferences)
 
 	return cmd
+}
+
+func NewCmdExtensionBrowse(cfg config.Config) *cobra.Command {
+	var flags struct {
+		Catalog string
+	}
+
+	cmd := &cobra.Command{
+		Use:   "browse",
+		Short: "Browse the extension catalog",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return tui.Draw(tui.NewCatalogPage(catalogSource(cfg, flags.Catalog)))
+		},
+	}
+
+	cmd.Flags().StringVar(&flags.Catalog, "catalog", "", "url or path of the catalog")
+	return cmd
+}
+
+// catalogSource returns the catalog to read, the flag overrides the config.
+func catalogSource(cfg config.Config, flag string) string {
+	if flag != "" {
+		return flag
+	}
+
+	if cfg.Catalog != "" {
+		return cfg.Catalog
+	}
 
+	return catalog.DefaultURL
 }
 
 func NewCmdExtensionRename(cfg config.Config) *cobra.Command {
@@ -223,11 +383,21 @@ func NewCmdExtensionRename(cfg config.Config) *cobra.Command {
 				return fmt.Errorf("extension %s already exists", args[1])
 			}
 
+			if err := extensions.ValidateAlias(args[1]); err != nil {
+				return err
+			}
+
 			extension, ok := cfg.Extensions[args[0]]
 			if !ok {
 				return fmt.Errorf("extension %s not found", args[0])
 			}
 
+			preferences, err := secrets.Rename(args[0], args[1], extension.Preferences)
+			if err != nil {
+				return fmt.Errorf("failed to move secrets: %w", err)
+			}
+			extension.Preferences = preferences
+
 			delete(cfg.Extensions, args[0])
 			cfg.Extensions[args[1]] = extension
 
@@ -235,6 +405,10 @@ func NewCmdExtensionRename(cfg config.Config) *cobra.Command {
 				return fmt.Errorf("failed to save config: %w", err)
 			}
 
+			if err := extensions.RenameStorage(args[0], args[1]); err != nil {
+				return fmt.Errorf("failed to move storage: %w", err)
+			}
+
 			cmd.Printf("✅ Renamed %s to %s\n", args[0], args[1])
 			return nil
 		},
@@ -243,7 +417,9 @@ func NewCmdExtensionRename(cfg config.Config) *cobra.Command {
 
 func NewCmdExtensionUpgrade(cfg config.Config) *cobra.Command {
 	flags := struct {
-		All bool
+		All    bool
+		Yes    bool
+		DryRun bool
 	}{}
 
 	cmd := &cobra.Command{
@@ -259,38 +435,119 @@ func NewCmdExtensionUpgrade(cfg config.Config) *cobra.Command {
 			return nil
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if len(args) > 0 {
-				extension, ok := cfg.Extensions[args[0]]
+			upgrade := func(alias string) error {
+				extensionConfig, ok := cfg.Extensions[alias]
 				if !ok {
-					return fmt.Errorf("extension %s not found", args[0])
+					return fmt.Errorf("extension %s not found", alias)
+				}
+
+				if flags.DryRun {
+					changes, err := extensions.PreviewUpgrade(extensionConfig)
+					if err != nil {
+						return fmt.Errorf("failed to check extension %s: %w", alias, err)
+					}
+
+					if changes.Empty() {
+						cmd.Printf("%s: no changes\n", alias)
+						return nil
+					}
+
+					cmd.Printf("%s%s\n", alias, formatVersionChange(changes))
+					printChanges(cmd, changes)
+					return nil
 				}
 
-				if err := extensions.Upgrade(extension); err != nil {
-					return fmt.Errorf("failed to upgrade extension: %w", err)
+				changes, err := extensions.Upgrade(extensionConfig)
+				if err != nil {
+					return fmt.Errorf("failed to upgrade extension %s: %w", alias, err)
 				}
 
-				cmd.Printf("✅ Upgraded %s\n", args[0])
+				if err := reviewPermissions(cmd, cfg, alias, flags.Yes); err != nil {
+					return err
+				}
+
+				cmd.Printf("✅ Upgraded %s%s\n", alias, formatVersionChange(changes))
+				printChanges(cmd, changes)
 				return nil
 			}
 
-			cmd.Printf("Upgrading %d extensions...\n\n", len(cfg.Extensions))
-			for alias, extension := range cfg.Extensions {
-				if err := extensions.Upgrade(extension); err != nil {
-					return fmt.Errorf("failed to upgrade extension %s: %w", alias, err)
-				}
+			if len(args) > 0 {
+				return upgrade(args[0])
+			}
 
-				cmd.Printf("✅ Upgraded %s\n", alias)
+			aliases := cfg.Aliases()
+			sort.Strings(aliases)
+
+			if flags.DryRun {
+				cmd.Printf("Checking %d extensions...\n\n", len(aliases))
+			} else {
+				cmd.Printf("Upgrading %d extensions...\n\n", len(aliases))
 			}
 
-			cmd.Printf("\n✅ Upgraded all extensions\n")
+			for _, alias := range aliases {
+				if err := upgrade(alias); err != nil {
+					return err
+				}
+			}
+
+			if !flags.DryRun {
+				cmd.Printf("\n✅ Upgraded all extensions\n")
+			}
 			return nil
 		},
 	}
 
 	cmd.Flags().BoolVar(&flags.All, "all", false, "upgrade all extensions")
+	cmd.Flags().BoolVarP(&flags.Yes, "yes", "y", false, "approve new permissions requested by the extensions")
+	cmd.Flags().BoolVar(&flags.DryRun, "dry-run", false, "show the changes without upgrading")
 	return cmd
 }
 
+func formatVersionChange(changes extensions.Changes) string {
+	if changes.OldVersion == changes.NewVersion {
+		if changes.NewVersion == "" {
+			return ""
+		}
+
+		return fmt.Sprintf(" (%s)", changes.NewVersion)
+	}
+
+	oldVersion := changes.OldVersion
+	if oldVersion == "" {
+		oldVersion = "unversioned"
+	}
+
+	newVersion := changes.NewVersion
+	if newVersion == "" {
+		newVersion = "unversioned"
+	}
+
+	return fmt.Sprintf(" (%s -> %s)", oldVersion, newVersion)
+}
+
+func printChanges(cmd *cobra.Command, changes extensions.Changes) {
+	for _, command := range changes.AddedCommands {
+		cmd.Printf("  + command %s\n", command)
+	}
+
+	for _, command := range changes.RemovedCommands {
+		cmd.Printf("  - command %s\n", command)
+	}
+
+	for _, param := range changes.RequiredParams {
+		cmd.Printf("  ! param %s is now required\n", param)
+	}
+
+	for _, preference := range changes.AddedPreferences {
+		if preference.Required {
+			cmd.Printf("  + preference %s (required)\n", preference.Name)
+			continue
+		}
+
+		cmd.Printf("  + preference %s\n", preference.Name)
+	}
+}
+
 func NewCmdExtensionList(cfg config.Config) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:     "list",
@@ -309,9 +566,21 @@ func NewCmdExtensionList(cfg config.Config) *cobra.Command {
 				t = tableprinter.New(os.Stdout, false, 0)
 			}
 
-			for alias, extension := range cfg.Extensions {
+			aliases := cfg.Aliases()
+			sort.Strings(aliases)
+
+			for _, alias := range aliases {
+				extensionConfig := cfg.Extensions[alias]
+
+				// the version is left blank if the extension cannot be loaded
+				var version string
+				if extension, err := extensions.LoadExtension(extensionConfig.Origin); err == nil {
+					version = extension.Manifest.Version
+				}
+
 				t.AddField(alias)
-				t.AddField(extension.Origin)
+				t.AddField(version)
+				t.AddField(extensionConfig.Origin)
 				t.EndRow()
 			}
 
@@ -323,7 +592,11 @@ func NewCmdExtensionList(cfg config.Config) *cobra.Command {
 }
 
 func NewCmdExtensionRemove(cfg config.Config) *cobra.Command {
-	return &cobra.Command{
+	var flags struct {
+		Yes bool
+	}
+
+	cmd := &cobra.Command{
 		Use:     "remove <alias>",
 		Short:   "Remove sunbeam extensions",
 		Aliases: []string{"rm", "uninstall"},
@@ -344,6 +617,33 @@ func NewCmdExtensionRemove(cfg config.Config) *cobra.Command {
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			for _, arg := range args {
+				if _, ok := cfg.Extensions[arg]; !ok {
+					return fmt.Errorf("extension %s not found", arg)
+				}
+			}
+
+			if !flags.Yes {
+				ok, err := confirm(cmd, fmt.Sprintf("Remove %s?", strings.Join(args, ", ")))
+				if err != nil {
+					return err
+				}
+
+				if !ok {
+					return fmt.Errorf("extensions were not removed")
+				}
+			}
+
+			removed := make([]string, 0, len(args))
+			for _, arg := range args {
+				removed = append(removed, cfg.Extensions[arg].Origin)
+				if err := secrets.Remove(cfg.Extensions[arg].Preferences); err != nil {
+					return fmt.Errorf("failed to remove secrets: %w", err)
+				}
+
+				if err := extensions.RemoveStorage(arg); err != nil {
+					return fmt.Errorf("failed to remove storage: %w", err)
+				}
+
 				delete(cfg.Extensions, arg)
 			}
 
@@ -351,6 +651,41 @@ func NewCmdExtensionRemove(cfg config.Config) *cobra.Command {
 				return fmt.Errorf("failed to save config: %w", err)
 			}
 
+			origins := make(map[string]bool)
+			for _, extension := range cfg.Extensions {
+				origins[extension.Origin] = true
+			}
+
+			// the cached outputs are shared by the aliases of an origin
+			for _, origin := range removed {
+				if origins[origin] {
+					continue
+				}
+
+				if err := extensions.RemoveOutputs(origin); err != nil {
+					return fmt.Errorf("failed to remove cached outputs: %w", err)
+				}
+			}
+
+			lockfile, err := config.LoadLockfile(config.LockPath())
+			if err != nil {
+				return err
+			}
+
+			var pruned bool
+			for origin := range lockfile.Extensions {
+				if !origins[origin] {
+					delete(lockfile.Extensions, origin)
+					pruned = true
+				}
+			}
+
+			if pruned {
+				if err := lockfile.Save(); err != nil {
+					return err
+				}
+			}
+
 			if len(args) == 1 {
 				cmd.Printf("✅ Removed %s\n", args[0])
 				return nil
@@ -360,6 +695,9 @@ func NewCmdExtensionRemove(cfg config.Config) *cobra.Command {
 			return nil
 		},
 	}
+
+	cmd.Flags().BoolVarP(&flags.Yes, "yes", "y", false, "do not ask for confirmation")
+	return cmd
 }
 
 func NewCmdExtensionPublish() *cobra.Command {
@@ -445,7 +783,9 @@ func NewCmdExtensionPublish() *cobra.Command {
 }
 
 func NewCmdExtensionConfigure(cfg config.Config) *cobra.Command {
-	return &cobra.Command{
+	var flags preferencesFlags
+
+	cmd := &cobra.Command{
 		Use:       "configure <alias>",
 		Short:     "Configure extension preferences",
 		Aliases:   []string{"config"},
@@ -466,15 +806,41 @@ func NewCmdExtensionConfigure(cfg config.Config) *cobra.Command {
 				return fmt.Errorf("extension %s has no preferences", args[0])
 			}
 
+			if flags.changed() {
+				preferences, err := updatePreferences(args[0], extension.Manifest.Preferences, extensionConfig.Preferences, flags)
+				if err != nil {
+					return err
+				}
+
+				extensionConfig.Preferences = preferences
+				cfg.Extensions[args[0]] = extensionConfig
+				if err := cfg.Save(); err != nil {
+					return fmt.Errorf("failed to save config: %w", err)
+				}
+
+				cmd.Printf("✅ Configured %s\n", args[0])
+				return nil
+			}
+
+			preferences, err := secrets.Reveal(extensionConfig.Preferences)
+			if err != nil {
+				return err
+			}
+
 			var inputs []types.Input
 			for _, input := range extension.Manifest.Preferences {
-				input.Default = extensionConfig.Preferences[input.Name]
+				input.Default = preferences[input.Name]
 				input.Required = true
 				inputs = append(inputs, input)
 			}
 
 			form := tui.NewForm(func(m map[string]any) tea.Msg {
-				extensionConfig.Preferences = m
+				preferences, err := secrets.Protect(args[0], extension.Manifest.Preferences, m)
+				if err != nil {
+					return err
+				}
+
+				extensionConfig.Preferences = preferences
 				cfg.Extensions[args[0]] = extensionConfig
 				if err := cfg.Save(); err != nil {
 					return err
@@ -486,4 +852,266 @@ func NewCmdExtensionConfigure(cfg config.Config) *cobra.Command {
 			return tui.Draw(form)
 		},
 	}
+
+	addPreferencesFlags(cmd, &flags)
+	return cmd
+}
+
+// reviewPermissions asks the user to approve the permissions of an upgraded extension, if they were extended.
+// Dropping the permissions block of a sandboxed extension requests every permission.
+func reviewPermissions(cmd *cobra.Command, cfg config.Config, alias string, yes bool) error {
+	extensionConfig := cfg.Extensions[alias]
+	extension, err := extensions.LoadExtension(extensionConfig.Origin)
+	if err != nil {
+		return fmt.Errorf("failed to load extension %s: %w", alias, err)
+	}
+
+	extension.Config = extensionConfig
+	permissions := extension.RequestedPermissions()
+	if permissions == nil {
+		return nil
+	}
+
+	if extensionConfig.Permissions != nil && extensionConfig.Permissions.Includes(*permissions) {
+		return nil
+	}
+
+	if err := approvePermissions(cmd, alias, *permissions, yes); err != nil {
+		return err
+	}
+
+	extensionConfig.Permissions = permissions
+	cfg.Extensions[alias] = extensionConfig
+	if err := cfg.Save(); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+
+	return nil
+}
+
+func approvePermissions(cmd *cobra.Command, alias string, permissions types.Permissions, yes bool) error {
+	cmd.PrintErrf("Extension %s requests the following permissions:\n\n%s\n\n", alias, extensions.FormatPermissions(permissions))
+	if !sandbox.Supported() {
+		cmd.PrintErrf("Warning: permissions cannot be enforced on this system.\n\n")
+	}
+
+	if yes {
+		return nil
+	}
+
+	ok, err := confirm(cmd, "Approve these permissions?")
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return fmt.Errorf("permissions of extension %s were not approved", alias)
+	}
+
+	return nil
+}
+
+// confirm asks a yes/no question on the terminal, defaulting to no.
+func confirm(cmd *cobra.Command, question string) (bool, error) {
+	if !isatty.IsTerminal(os.Stdin.Fd()) {
+		return false, fmt.Errorf("cannot ask for confirmation: stdin is not a terminal, use --yes")
+	}
+
+	cmd.PrintErrf("%s [y/N] ", question)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false, err
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+func NewCmdExtensionDev() *cobra.Command {
+	return &cobra.Command{
+		Use:   "dev <entrypoint>",
+		Short: "Run an extension without installing it, and reload it on changes",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			origin, err := filepath.Abs(args[0])
+			if err != nil {
+				return err
+			}
+
+			alias, err := extractAlias(origin)
+			if err != nil {
+				return fmt.Errorf("failed to get alias: %w", err)
+			}
+
+			history, err := history.Load(history.Path)
+			if err != nil {
+				return err
+			}
+
+			rootList := tui.NewRootList(fmt.Sprintf("%s (dev)", alias), history, func() (config.Config, []types.ListItem, error) {
+				extension, err := extensions.LoadExtension(origin)
+				if err != nil {
+					return config.Config{}, nil, err
+				}
+
+				// every command is listed, and the permissions are approved by the author of the extension
+				extensionConfig := config.ExtensionConfig{
+					Origin:      origin,
+					Permissions: extension.Manifest.Permissions,
+				}
+				for _, command := range extension.Manifest.Commands {
+					extensionConfig.Root = append(extensionConfig.Root, command.Name)
+				}
+
+				cfg := config.Config{
+					Extensions: map[string]config.ExtensionConfig{
+						alias: extensionConfig,
+					},
+				}
+
+				return cfg, extensionListItems(alias, extension, extensionConfig), nil
+			})
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			changes, err := watcher.Watch(ctx, origin)
+			if err != nil {
+				return fmt.Errorf("failed to watch extension: %w", err)
+			}
+
+			return tui.DrawDev(rootList, changes)
+		},
+	}
+}
+
+func NewCmdExtensionStorage(cfg config.Config) *cobra.Command {
+	var flags struct {
+		Clear     bool
+		CacheOnly bool
+		Yes       bool
+	}
+
+	cmd := &cobra.Command{
+		Use:   "storage [alias...]",
+		Short: "Inspect or clear the data and cache directories of extensions",
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return cfg.Aliases(), cobra.ShellCompDirectiveNoFileComp
+		},
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if flags.Clear && len(args) == 0 {
+				return fmt.Errorf("provide the extensions to clear")
+			}
+
+			if flags.CacheOnly && !flags.Clear {
+				return fmt.Errorf("--cache-only can only be used with --clear")
+			}
+
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			for _, arg := range args {
+				if _, ok := cfg.Extensions[arg]; !ok {
+					return fmt.Errorf("extension %s not found", arg)
+				}
+
+				if err := extensions.ValidateAlias(arg); err != nil {
+					return err
+				}
+			}
+
+			aliases := args
+			if len(aliases) == 0 {
+				aliases = cfg.Aliases()
+				sort.Strings(aliases)
+			}
+
+			if flags.Clear {
+				for _, alias := range aliases {
+					dirs := []string{extensions.CacheDir(alias)}
+					if !flags.CacheOnly {
+						dirs = append(dirs, extensions.DataDir(alias))
+					}
+
+					if !flags.Yes {
+						ok, err := confirm(cmd, fmt.Sprintf("Delete %s?", strings.Join(dirs, " and ")))
+						if err != nil {
+							return err
+						}
+
+						if !ok {
+							continue
+						}
+					}
+
+					for _, dir := range dirs {
+						if err := os.RemoveAll(dir); err != nil {
+							return fmt.Errorf("failed to clear %s: %w", dir, err)
+						}
+					}
+
+					cmd.Printf("✅ Cleared %s\n", alias)
+				}
+
+				return nil
+			}
+
+			var t tableprinter.TablePrinter
+			if isatty.IsTerminal(os.Stdout.Fd()) {
+				w, _, err := term.GetSize(int(os.Stdout.Fd()))
+				if err != nil {
+					return err
+				}
+				t = tableprinter.New(os.Stdout, true, w)
+			} else {
+				t = tableprinter.New(os.Stdout, false, 0)
+			}
+
+			for _, alias := range aliases {
+				dataSize, err := extensions.DirSize(extensions.DataDir(alias))
+				if err != nil {
+					return err
+				}
+
+				cacheSize, err := extensions.DirSize(extensions.CacheDir(alias))
+				if err != nil {
+					return err
+				}
+
+				t.AddField(alias)
+				t.AddField(extensions.DataDir(alias))
+				t.AddField(formatSize(dataSize))
+				t.AddField(extensions.CacheDir(alias))
+				t.AddField(formatSize(cacheSize))
+				t.EndRow()
+			}
+
+			return t.Render()
+		},
+	}
+
+	cmd.Flags().BoolVar(&flags.Clear, "clear", false, "delete the data and cache of the extensions")
+	cmd.Flags().BoolVar(&flags.CacheOnly, "cache-only", false, "only delete the cache")
+	cmd.Flags().BoolVarP(&flags.Yes, "yes", "y", false, "do not ask for confirmation")
+
+	return cmd
+}
+
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
 }
@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pomdtr/sunbeam/internal/config"
+	"github.com/pomdtr/sunbeam/internal/extensions"
+	"github.com/pomdtr/sunbeam/internal/types"
+)
+
+func TestRemoveCachedOutputs(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	const shared, single = "https://example.com/shared.sh", "https://example.com/single.sh"
+
+	configPath := filepath.Join(t.TempDir(), "sunbeam.json")
+	if err := os.WriteFile(configPath, []byte(`{"extensions": {
+		"first": {"origin": "`+shared+`"},
+		"second": {"origin": "`+shared+`"},
+		"single": {"origin": "`+single+`"}
+	}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	previous := config.Path
+	config.Path = configPath
+	t.Cleanup(func() { config.Path = previous })
+
+	payload := types.Payload{Command: "list"}
+	var cached []extensions.Extension
+	for _, origin := range []string{shared, single} {
+		extension := extensions.Extension{
+			Origin:   origin,
+			Manifest: types.Manifest{Commands: []types.CommandSpec{{Name: "list", Mode: types.CommandModeFilter, Cache: 60}}},
+		}
+
+		if err := extension.CacheOutput(payload, []byte(`{"items": []}`)); err != nil {
+			t.Fatal(err)
+		}
+		cached = append(cached, extension)
+	}
+
+	for _, tc := range []struct {
+		alias string
+		// outputs reports whether the outputs of the shared and single origins are still cached
+		outputs [2]bool
+	}{
+		{alias: "first", outputs: [2]bool{true, true}},
+		{alias: "single", outputs: [2]bool{true, false}},
+		{alias: "second", outputs: [2]bool{false, false}},
+	} {
+		cfg, err := config.Load(configPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		cmd := NewCmdExtensionRemove(cfg)
+		cmd.SetArgs([]string{"--yes", tc.alias})
+		cmd.SetOut(io.Discard)
+		if err := cmd.Execute(); err != nil {
+			t.Fatalf("remove %s: %v", tc.alias, err)
+		}
+
+		for i, extension := range cached {
+			_, _, err := extension.CachedOutput(payload)
+			if tc.outputs[i] && err != nil {
+				t.Errorf("after removing %s: outputs of %s were deleted: %v", tc.alias, extension.Origin, err)
+			} else if !tc.outputs[i] && !os.IsNotExist(err) {
+				t.Errorf("after removing %s: outputs of %s are still cached", tc.alias, extension.Origin)
+			}
+		}
+	}
+}
@@ -0,0 +1,299 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/acarl005/stripansi"
+	"github.com/pomdtr/sunbeam/internal/extensions"
+	"github.com/pomdtr/sunbeam/internal/schemas"
+	"github.com/pomdtr/sunbeam/internal/types"
+	"github.com/spf13/cobra"
+)
+
+// SnapshotDir is the directory, relative to the fixtures, where the outputs of the commands are stored.
+const SnapshotDir = "__snapshots__"
+
+func NewCmdExtensionTest() *cobra.Command {
+	var flags struct {
+		Fixtures string
+		Update   bool
+	}
+
+	cmd := &cobra.Command{
+		Use:   "test <entrypoint>",
+		Short: "Run the commands of an extension against fixtures",
+		Long: `Run the commands of an extension against fixtures.
+
+Each fixture is a json file containing the payload of a command, ex: {"command": "list-issues", "params": {"repo": "pomdtr/sunbeam"}}.
+Fixtures are read from the tests directory of the extension, or from tests/<entrypoint> for single file scripts.
+Each fixture runs with an empty data and cache directory.
+The output of the command is validated against the schema of the command mode, and compared with the snapshot stored in the __snapshots__ directory.
+The items emitted by stream commands are snapshotted as an array.
+Missing snapshots are created.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			origin, err := filepath.Abs(args[0])
+			if err != nil {
+				return err
+			}
+
+			extension, err := extensions.LoadExtension(origin)
+			if err != nil {
+				return fmt.Errorf("failed to load extension: %w", err)
+			}
+			// the author of the extension approves its permissions
+			extension.Config.Permissions = extension.Manifest.Permissions
+			if extension.Alias, err = extractAlias(origin); err != nil {
+				return fmt.Errorf("failed to get alias: %w", err)
+			}
+
+			fixturesDir := flags.Fixtures
+			if fixturesDir == "" {
+				fixturesDir = defaultFixturesDir(extension)
+			}
+
+			fixtures, err := filepath.Glob(filepath.Join(fixturesDir, "*.json"))
+			if err != nil {
+				return err
+			}
+
+			if len(fixtures) == 0 {
+				return fmt.Errorf("no fixtures found in %s", fixturesDir)
+			}
+			sort.Strings(fixtures)
+
+			var failed int
+			for _, fixture := range fixtures {
+				name := strings.TrimSuffix(filepath.Base(fixture), ".json")
+				snapshotPath := filepath.Join(fixturesDir, SnapshotDir, filepath.Base(fixture))
+
+				status, err := runFixture(extension, fixture, snapshotPath, flags.Update)
+				if err != nil {
+					failed++
+					cmd.Printf("❌ %s\n%s\n", name, indent(err.Error()))
+					continue
+				}
+
+				cmd.Printf("✅ %s%s\n", name, status)
+			}
+
+			if failed > 0 {
+				return fmt.Errorf("%d of %d tests failed", failed, len(fixtures))
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&flags.Fixtures, "fixtures", "", "directory containing the fixtures, defaults to the tests directory of the extension")
+	cmd.Flags().BoolVarP(&flags.Update, "update", "u", false, "rewrite the snapshots")
+	return cmd
+}
+
+// defaultFixturesDir returns the tests directory of an extension.
+// Scripts often share a directory, so the fixtures of a script are stored in a subdirectory named after it.
+func defaultFixturesDir(extension extensions.Extension) string {
+	if extension.Dir != "" {
+		return filepath.Join(extension.Dir, "tests")
+	}
+
+	return filepath.Join(filepath.Dir(extension.Entrypoint), "tests", filepath.Base(extension.Entrypoint))
+}
+
+// runFixture runs the payload of a fixture, and compares the output with the snapshot.
+// It returns a suffix describing what happened to the snapshot.
+func runFixture(extension extensions.Extension, fixturePath string, snapshotPath string, update bool) (string, error) {
+	fixtureBytes, err := os.ReadFile(fixturePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read fixture: %w", err)
+	}
+
+	var input types.Payload
+	if err := json.Unmarshal(fixtureBytes, &input); err != nil {
+		return "", fmt.Errorf("failed to decode fixture: %w", err)
+	}
+
+	command, ok := extension.Command(input.Command)
+	if !ok {
+		return "", fmt.Errorf("command %s not found", input.Command)
+	}
+
+	if command.Mode == types.CommandModeTTY {
+		return "", fmt.Errorf("command %s runs in a terminal, it cannot be tested", command.Name)
+	}
+
+	// each fixture starts from an empty storage, and does not touch the storage of the installed extension
+	storageDir, err := os.MkdirTemp("", "sunbeam-test-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create storage directory: %w", err)
+	}
+	defer os.RemoveAll(storageDir)
+	extension.StorageDir = storageDir
+
+	ctx := context.Background()
+	if timeout := extension.CommandTimeout(command.Name); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	c, err := extension.CmdContext(ctx, input)
+	if err != nil {
+		return "", err
+	}
+
+	output, err := c.Output()
+	if ctx.Err() == context.DeadlineExceeded {
+		return "", &extensions.TimeoutError{Command: command.Name, Timeout: extension.CommandTimeout(command.Name)}
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return "", fmt.Errorf("command failed: %s", stripansi.Strip(string(exitErr.Stderr)))
+	} else if err != nil {
+		return "", err
+	}
+
+	switch command.Mode {
+	case types.CommandModeSearch, types.CommandModeFilter:
+		if command.Streams() {
+			items, err := streamItems(output)
+			if err != nil {
+				return "", fmt.Errorf("list item is invalid: %s", err)
+			}
+
+			// the items are snapshotted as an array, to be indented
+			if output, err = json.Marshal(items); err != nil {
+				return "", err
+			}
+			break
+		}
+
+		if err := schemas.ValidateList(output); err != nil {
+			return "", fmt.Errorf("list is invalid: %s", err)
+		}
+	case types.CommandModeDetail:
+		if err := schemas.ValidateDetail(output); err != nil {
+			return "", fmt.Errorf("detail is invalid: %s", err)
+		}
+	}
+
+	if command.Mode != types.CommandModeSilent {
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, bytes.TrimSpace(output), "", "  "); err != nil {
+			return "", err
+		}
+		buf.WriteString("\n")
+		output = buf.Bytes()
+	}
+
+	snapshot, err := os.ReadFile(snapshotPath)
+	if os.IsNotExist(err) || update {
+		if err := os.MkdirAll(filepath.Dir(snapshotPath), 0755); err != nil {
+			return "", fmt.Errorf("failed to create directory: %w", err)
+		}
+
+		if err := os.WriteFile(snapshotPath, output, 0644); err != nil {
+			return "", fmt.Errorf("failed to write snapshot: %w", err)
+		}
+
+		if update {
+			return " (snapshot updated)", nil
+		}
+
+		return " (snapshot written)", nil
+	} else if err != nil {
+		return "", fmt.Errorf("failed to read snapshot: %w", err)
+	}
+
+	if !bytes.Equal(snapshot, output) {
+		return "", fmt.Errorf("output does not match the snapshot, run with --update to rewrite it\n%s", diffLines(string(snapshot), string(output)))
+	}
+
+	return "", nil
+}
+
+// streamItems returns the list items emitted by a stream command, one per line.
+func streamItems(output []byte) ([]json.RawMessage, error) {
+	items := make([]json.RawMessage, 0)
+	for _, line := range bytes.Split(output, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+
+		if err := schemas.ValidateListItem(line); err != nil {
+			return nil, err
+		}
+
+		items = append(items, line)
+	}
+
+	return items, nil
+}
+
+// diffLines returns the lines removed from and added to a text, prefixed with - and +.
+func diffLines(before string, after string) string {
+	a := strings.Split(strings.TrimSuffix(before, "\n"), "\n")
+	b := strings.Split(strings.TrimSuffix(after, "\n"), "\n")
+
+	// lengths of the longest common subsequences of the suffixes
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var lines []string
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, "- "+a[i])
+			i++
+		default:
+			lines = append(lines, "+ "+b[j])
+			j++
+		}
+	}
+
+	for ; i < len(a); i++ {
+		lines = append(lines, "- "+a[i])
+	}
+
+	for ; j < len(b); j++ {
+		lines = append(lines, "+ "+b[j])
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func indent(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = "  " + line
+	}
+
+	return strings.Join(lines, "\n")
+}
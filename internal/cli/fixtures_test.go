@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pomdtr/sunbeam/internal/extensions"
+	"github.com/pomdtr/sunbeam/internal/types"
+)
+
+func TestDefaultFixturesDir(t *testing.T) {
+	for _, tc := range []struct {
+		extension extensions.Extension
+		want      string
+	}{
+		{
+			extension: extensions.Extension{Entrypoint: "/src/github.sh"},
+			want:      "/src/tests/github.sh",
+		},
+		{
+			extension: extensions.Extension{Entrypoint: "/src/tldr.py"},
+			want:      "/src/tests/tldr.py",
+		},
+		{
+			extension: extensions.Extension{Entrypoint: "/src/github/main.ts", Dir: "/src/github"},
+			want:      "/src/github/tests",
+		},
+	} {
+		if got := defaultFixturesDir(tc.extension); got != tc.want {
+			t.Errorf("defaultFixturesDir(%s) = %s, want %s", tc.extension.Entrypoint, got, tc.want)
+		}
+	}
+}
+
+// counter prints how many times it ran, according to its data directory.
+const counter = `#!/bin/sh
+count=$(cat "$SUNBEAM_EXTENSION_DATA_DIR/count" 2>/dev/null || echo 0)
+count=$((count + 1))
+echo "$count" > "$SUNBEAM_EXTENSION_DATA_DIR/count"
+echo "{\"text\": \"run $count\"}"
+`
+
+func TestRunFixtureStorage(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	dir := t.TempDir()
+	entrypoint := filepath.Join(dir, "counter.sh")
+	if err := os.WriteFile(entrypoint, []byte(counter), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	fixture := filepath.Join(dir, "count.json")
+	if err := os.WriteFile(fixture, []byte(`{"command": "count"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	extension := extensions.Extension{
+		Type:       extensions.ExtensionTypeLocal,
+		Entrypoint: entrypoint,
+		Alias:      "counter",
+		Manifest: types.Manifest{
+			Title:    "Counter",
+			Commands: []types.CommandSpec{{Name: "count", Title: "Count", Mode: types.CommandModeDetail}},
+		},
+	}
+
+	// the storage of the installed extension must not be read
+	if err := os.MkdirAll(extensions.DataDir("counter"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	countPath := filepath.Join(extensions.DataDir("counter"), "count")
+	if err := os.WriteFile(countPath, []byte("41\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	snapshotPath := filepath.Join(dir, SnapshotDir, "count.json")
+	for i, want := range []string{" (snapshot written)", ""} {
+		status, err := runFixture(extension, fixture, snapshotPath, false)
+		if err != nil {
+			t.Fatalf("run %d: %v", i+1, err)
+		}
+
+		if status != want {
+			t.Errorf("run %d: status = %q, want %q", i+1, status, want)
+		}
+	}
+
+	snapshot, err := os.ReadFile(snapshotPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := string(snapshot), "{\n  \"text\": \"run 1\"\n}\n"; got != want {
+		t.Errorf("snapshot = %q, want %q", got, want)
+	}
+
+	if count, err := os.ReadFile(countPath); err != nil || string(count) != "41\n" {
+		t.Errorf("installed storage = %q (%v), want it untouched", count, err)
+	}
+}
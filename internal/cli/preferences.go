@@ -0,0 +1,162 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pomdtr/sunbeam/internal/secrets"
+	"github.com/pomdtr/sunbeam/internal/types"
+	"github.com/spf13/cobra"
+)
+
+type preferencesFlags struct {
+	Set      []string
+	FromJSON string
+	Unset    []string
+}
+
+func (f preferencesFlags) changed() bool {
+	return len(f.Set) > 0 || f.FromJSON != "" || len(f.Unset) > 0
+}
+
+func addPreferencesFlags(cmd *cobra.Command, flags *preferencesFlags) {
+	cmd.Flags().StringArrayVar(&flags.Set, "set", nil, "set a preference, as name=value")
+	cmd.Flags().StringVar(&flags.FromJSON, "from-json", "", "set preferences from a json file, use - to read from stdin")
+	cmd.Flags().StringArrayVar(&flags.Unset, "unset", nil, "unset a preference")
+}
+
+// updatePreferences applies the preferences flags to the preferences of an extension,
+// and returns the preferences to write in the config.
+func updatePreferences(alias string, inputs []types.Input, current map[string]any, flags preferencesFlags) (map[string]any, error) {
+	preferences, err := secrets.Reveal(current)
+	if err != nil {
+		return nil, err
+	}
+
+	inputsByName := make(map[string]types.Input)
+	for _, input := range inputs {
+		inputsByName[input.Name] = input
+	}
+
+	lookup := func(name string) (types.Input, error) {
+		input, ok := inputsByName[name]
+		if !ok {
+			return types.Input{}, fmt.Errorf("extension %s has no preference %s", alias, name)
+		}
+
+		return input, nil
+	}
+
+	if flags.FromJSON != "" {
+		var content []byte
+		if flags.FromJSON == "-" {
+			content, err = io.ReadAll(os.Stdin)
+		} else {
+			content, err = os.ReadFile(flags.FromJSON)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read preferences: %w", err)
+		}
+
+		var values map[string]any
+		if err := json.Unmarshal(content, &values); err != nil {
+			return nil, fmt.Errorf("failed to decode preferences: %w", err)
+		}
+
+		for name, value := range values {
+			input, err := lookup(name)
+			if err != nil {
+				return nil, err
+			}
+
+			value, err := checkPreference(input, value)
+			if err != nil {
+				return nil, err
+			}
+			preferences[name] = value
+		}
+	}
+
+	for _, arg := range flags.Set {
+		name, raw, ok := strings.Cut(arg, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid preference %s, expected name=value", arg)
+		}
+
+		input, err := lookup(name)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := parsePreference(input, raw)
+		if err != nil {
+			return nil, err
+		}
+		preferences[name] = value
+	}
+
+	removed := make(map[string]any)
+	for _, name := range flags.Unset {
+		if _, err := lookup(name); err != nil {
+			return nil, err
+		}
+
+		delete(preferences, name)
+		if value, ok := current[name]; ok {
+			removed[name] = value
+		}
+	}
+
+	if err := secrets.Remove(removed); err != nil {
+		return nil, fmt.Errorf("failed to remove secrets: %w", err)
+	}
+
+	return secrets.Protect(alias, inputs, preferences)
+}
+
+// parsePreference converts a value passed on the command line to the type of the input.
+func parsePreference(input types.Input, raw string) (any, error) {
+	switch input.Type {
+	case types.InputCheckbox:
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("preference %s must be a boolean, got %q", input.Name, raw)
+		}
+		return value, nil
+	case types.InputNumber:
+		value, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, fmt.Errorf("preference %s must be an integer, got %q", input.Name, raw)
+		}
+		return value, nil
+	default:
+		return raw, nil
+	}
+}
+
+// checkPreference verifies that a value decoded from json matches the type of the input.
+func checkPreference(input types.Input, value any) (any, error) {
+	switch input.Type {
+	case types.InputCheckbox:
+		if _, ok := value.(bool); !ok {
+			return nil, fmt.Errorf("preference %s must be a boolean", input.Name)
+		}
+		return value, nil
+	case types.InputNumber:
+		n, ok := value.(float64)
+		if !ok || n != math.Trunc(n) {
+			return nil, fmt.Errorf("preference %s must be an integer", input.Name)
+		}
+		return int(n), nil
+	default:
+		if _, ok := value.(string); !ok {
+			return nil, fmt.Errorf("preference %s must be a string", input.Name)
+		}
+		return value, nil
+	}
+}
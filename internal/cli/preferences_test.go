@@ -0,0 +1,140 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/pomdtr/sunbeam/internal/secrets"
+	"github.com/pomdtr/sunbeam/internal/types"
+)
+
+var preferenceInputs = []types.Input{
+	{Name: "name", Type: types.InputText},
+	{Name: "verbose", Type: types.InputCheckbox},
+	{Name: "limit", Type: types.InputNumber},
+	{Name: "token", Type: types.InputPassword},
+}
+
+func TestParsePreference(t *testing.T) {
+	for _, tc := range []struct {
+		input types.Input
+		raw   string
+		want  any
+		err   bool
+	}{
+		{input: preferenceInputs[0], raw: "sunbeam", want: "sunbeam"},
+		{input: preferenceInputs[0], raw: "", want: ""},
+		{input: preferenceInputs[0], raw: "a=b", want: "a=b"},
+		{input: preferenceInputs[1], raw: "true", want: true},
+		{input: preferenceInputs[1], raw: "0", want: false},
+		{input: preferenceInputs[1], raw: "yes", err: true},
+		{input: preferenceInputs[2], raw: "42", want: 42},
+		{input: preferenceInputs[2], raw: "-1", want: -1},
+		{input: preferenceInputs[2], raw: "4.2", err: true},
+		{input: preferenceInputs[2], raw: "", err: true},
+		{input: preferenceInputs[3], raw: "hunter2", want: "hunter2"},
+	} {
+		got, err := parsePreference(tc.input, tc.raw)
+		if tc.err {
+			if err == nil {
+				t.Errorf("%s=%q: expected an error", tc.input.Name, tc.raw)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s=%q: unexpected error: %v", tc.input.Name, tc.raw, err)
+		} else if got != tc.want {
+			t.Errorf("%s=%q: got %#v, want %#v", tc.input.Name, tc.raw, got, tc.want)
+		}
+	}
+}
+
+func TestCheckPreference(t *testing.T) {
+	for _, tc := range []struct {
+		input types.Input
+		value any
+		want  any
+		err   bool
+	}{
+		{input: preferenceInputs[0], value: "sunbeam", want: "sunbeam"},
+		{input: preferenceInputs[0], value: 42.0, err: true},
+		{input: preferenceInputs[1], value: true, want: true},
+		{input: preferenceInputs[1], value: "true", err: true},
+		{input: preferenceInputs[2], value: 42.0, want: 42},
+		{input: preferenceInputs[2], value: 4.2, err: true},
+		{input: preferenceInputs[2], value: "42", err: true},
+	} {
+		got, err := checkPreference(tc.input, tc.value)
+		if tc.err {
+			if err == nil {
+				t.Errorf("%s=%#v: expected an error", tc.input.Name, tc.value)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s=%#v: unexpected error: %v", tc.input.Name, tc.value, err)
+		} else if got != tc.want {
+			t.Errorf("%s=%#v: got %#v, want %#v", tc.input.Name, tc.value, got, tc.want)
+		}
+	}
+}
+
+func TestUpdatePreferences(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	jsonPath := filepath.Join(t.TempDir(), "preferences.json")
+	if err := os.WriteFile(jsonPath, []byte(`{"limit": 10, "verbose": true}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	preferences, err := updatePreferences("test", preferenceInputs, nil, preferencesFlags{
+		Set:      []string{"name=sunbeam", "token=hunter2", "limit=20"},
+		FromJSON: jsonPath,
+	})
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+
+	want := map[string]any{
+		"name":    "sunbeam",
+		"verbose": true,
+		"limit":   20,
+		"token":   secrets.Reference(secrets.Name("test", "token")),
+	}
+	if !reflect.DeepEqual(preferences, want) {
+		t.Errorf("preferences = %#v, want %#v", preferences, want)
+	}
+
+	preferences, err = updatePreferences("test", preferenceInputs, preferences, preferencesFlags{Unset: []string{"token", "verbose"}})
+	if err != nil {
+		t.Fatalf("unset: %v", err)
+	}
+
+	if _, ok := preferences["token"]; ok {
+		t.Errorf("token was not unset: %v", preferences)
+	}
+
+	store, err := secrets.Load(secrets.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := store.Secrets[secrets.Name("test", "token")]; ok {
+		t.Error("the secret of an unset preference was kept")
+	}
+
+	for _, flags := range []preferencesFlags{
+		{Set: []string{"missing=1"}},
+		{Set: []string{"name"}},
+		{Set: []string{"limit=many"}},
+		{Unset: []string{"missing"}},
+	} {
+		if _, err := updatePreferences("test", preferenceInputs, preferences, flags); err == nil {
+			t.Errorf("%+v: expected an error", flags)
+		}
+	}
+}
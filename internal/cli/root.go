@@ -35,6 +35,8 @@ func IsSunbeamRunning() bool {
 var configBytes []byte
 
 func NewRootCmd() (*cobra.Command, error) {
+	extensions.Version = Version
+
 	// rootCmd represents the base command when called without any subcommands
 	var rootCmd = &cobra.Command{
 		Use:          "sunbeam",
@@ -108,6 +110,7 @@ See https://pomdtr.github.io/sunbeam for more information.`,
 		},
 	}
 	rootCmd.AddCommand(versionCmd)
+	rootCmd.AddCommand(NewCmdSandbox())
 
 	if IsSunbeamRunning() {
 		return rootCmd, nil
@@ -145,6 +148,8 @@ See https://pomdtr.github.io/sunbeam for more information.`,
 			fmt.Fprintf(os.Stderr, "error loading extension %s: %s\n", alias, err)
 			continue
 		}
+		extension.Alias = alias
+		extension.Config = extensionConfig
 		extensionMap[alias] = extension
 
 		command, err := NewCmdCustom(alias, extension, extensionConfig)
@@ -175,6 +180,8 @@ See https://pomdtr.github.io/sunbeam for more information.`,
 				if err != nil {
 					continue
 				}
+				extension.Alias = alias
+				extension.Config = extensionConfig
 				extensionMap[alias] = extension
 				items = append(items, extensionListItems(alias, extension, extensionConfig)...)
 			}
@@ -256,11 +263,15 @@ func onelinerListItems(oneliners map[string]config.Oneliner) []types.ListItem {
 func extensionListItems(alias string, extension extensions.Extension, extensionConfig config.ExtensionConfig) []types.ListItem {
 	var items []types.ListItem
 
+	if !extension.IsSupported() {
+		return nil
+	}
+
 	var rootItems []types.RootItem
 	if extensionConfig.Root != nil {
 		for _, name := range extensionConfig.Root {
 			command, ok := extension.Command(name)
-			if !ok {
+			if !ok || !extension.SupportsCommand(command) {
 				continue
 			}
 			rootItems = append(rootItems, types.RootItem{
@@ -271,7 +282,14 @@ func extensionListItems(alias string, extension extensions.Extension, extensionC
 	} else {
 		rootItems = append(rootItems, extension.RootItems()...)
 	}
-	rootItems = append(rootItems, extensionConfig.Items...)
+
+	for _, item := range extensionConfig.Items {
+		if command, ok := extension.Command(item.Command); ok && !extension.SupportsCommand(command) {
+			continue
+		}
+
+		rootItems = append(rootItems, item)
+	}
 
 	for _, rootItem := range rootItems {
 		item := types.ListItem{
@@ -299,6 +317,13 @@ func extensionListItems(alias string, extension extensions.Extension, extensionC
 				Path:   extension.Entrypoint,
 				Reload: true,
 			})
+		} else if extension.Dir != "" {
+			item.Actions = append(item.Actions, types.Action{
+				Title: "Open Extension Directory",
+				Key:   "c",
+				Type:  types.ActionTypeOpen,
+				Path:  extension.Dir,
+			})
 		} else {
 			item.Actions = append(item.Actions, types.Action{
 				Title:   "View Source",
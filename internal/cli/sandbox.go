@@ -0,0 +1,34 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pomdtr/sunbeam/internal/sandbox"
+	"github.com/spf13/cobra"
+)
+
+func NewCmdSandbox() *cobra.Command {
+	var flags struct {
+		Policy string
+	}
+
+	cmd := &cobra.Command{
+		Use:    fmt.Sprintf("%s --policy <policy> -- <program> [args...]", sandbox.HelperCommand),
+		Short:  "Run a program with restricted filesystem access",
+		Hidden: true,
+		Args:   cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var policy sandbox.Policy
+			if err := json.Unmarshal([]byte(flags.Policy), &policy); err != nil {
+				return fmt.Errorf("invalid policy: %w", err)
+			}
+
+			return sandbox.Exec(policy, args)
+		},
+	}
+
+	cmd.Flags().StringVar(&flags.Policy, "policy", "{}", "sandbox policy, as json")
+	cmd.Flags().SetInterspersed(false)
+	return cmd
+}
@@ -38,14 +38,20 @@ func init() {
 type Config struct {
 	Oneliners  map[string]Oneliner        `json:"oneliners,omitempty"`
 	Extensions map[string]ExtensionConfig `json:"extensions,omitempty"`
+	Catalog    string                     `json:"catalog,omitempty"`
 	path       string                     `json:"-"`
 }
 
 type ExtensionConfig struct {
-	Root        []string         `json:"root,omitempty"`
-	Origin      string           `json:"origin,omitempty"`
-	Preferences map[string]any   `json:"preferences,omitempty"`
-	Items       []types.RootItem `json:"items,omitempty"`
+	Root        []string           `json:"root,omitempty"`
+	Origin      string             `json:"origin,omitempty"`
+	Preferences map[string]any     `json:"preferences,omitempty"`
+	Items       []types.RootItem   `json:"items,omitempty"`
+	Timeout     int                `json:"timeout,omitempty"`
+	Env         map[string]string  `json:"env,omitempty"`
+	AllowEnv    []string           `json:"allowEnv,omitempty"`
+	DenyEnv     []string           `json:"denyEnv,omitempty"`
+	Permissions *types.Permissions `json:"permissions,omitempty"`
 }
 
 type Oneliner struct {
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Lockfile records the checksum of every remote entrypoint, keyed by origin.
+type Lockfile struct {
+	Extensions map[string]LockEntry `json:"extensions"`
+	path       string               `json:"-"`
+}
+
+type LockEntry struct {
+	Sha256 string `json:"sha256"`
+}
+
+// LockPath returns the path of the lockfile, next to the config file.
+func LockPath() string {
+	return filepath.Join(filepath.Dir(Path), "sunbeam.lock")
+}
+
+func LoadLockfile(lockPath string) (Lockfile, error) {
+	lockBytes, err := os.ReadFile(lockPath)
+	if os.IsNotExist(err) {
+		return Lockfile{
+			Extensions: make(map[string]LockEntry),
+			path:       lockPath,
+		}, nil
+	} else if err != nil {
+		return Lockfile{}, fmt.Errorf("failed to load lockfile: %w", err)
+	}
+
+	var lockfile Lockfile
+	if err := json.Unmarshal(lockBytes, &lockfile); err != nil {
+		return Lockfile{}, fmt.Errorf("failed to unmarshal lockfile: %w", err)
+	}
+
+	if lockfile.Extensions == nil {
+		lockfile.Extensions = make(map[string]LockEntry)
+	}
+	lockfile.path = lockPath
+
+	return lockfile, nil
+}
+
+func (l Lockfile) Save() error {
+	lockBytes, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode lockfile: %w", err)
+	}
+
+	if err := os.WriteFile(l.path, append(lockBytes, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write lockfile: %w", err)
+	}
+
+	return nil
+}
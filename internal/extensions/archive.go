@@ -0,0 +1,425 @@
+package extensions
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/acarl005/stripansi"
+)
+
+func IsGitRepository(origin string) bool {
+	if strings.HasPrefix(origin, "git+") {
+		return true
+	}
+
+	originUrl, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+
+	return strings.HasSuffix(originUrl.Path, ".git")
+}
+
+func IsArchive(origin string) bool {
+	return archiveFormat(origin) != ""
+}
+
+func archiveFormat(origin string) string {
+	originUrl, err := url.Parse(origin)
+	if err != nil {
+		return ""
+	}
+
+	switch {
+	case strings.HasSuffix(originUrl.Path, ".tar.gz"), strings.HasSuffix(originUrl.Path, ".tgz"):
+		return "tar.gz"
+	case strings.HasSuffix(originUrl.Path, ".zip"):
+		return "zip"
+	default:
+		return ""
+	}
+}
+
+// fetchRepository clones a git repository to the extension dir.
+// A ref can be specified in the fragment of the origin, ex: git+https://github.com/user/repo#v1.0.0
+func fetchRepository(origin string, extensionDir string) (Metadata, error) {
+	repository, ref, _ := strings.Cut(strings.TrimPrefix(origin, "git+"), "#")
+
+	srcDir, err := replaceSources(extensionDir, func(dir string) (string, error) {
+		args := []string{"clone", "--depth", "1"}
+		if ref != "" {
+			args = append(args, "--branch", ref)
+		}
+		args = append(args, "--", repository, dir)
+
+		cmd := exec.Command("git", args...)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return "", fmt.Errorf("failed to clone repository: %s", stripansi.Strip(string(output)))
+		}
+
+		return dir, nil
+	})
+	if err != nil {
+		return Metadata{}, err
+	}
+
+	return writeDirMetadata(origin, srcDir, extensionDir)
+}
+
+// fetchArchive downloads a tarball or a zip archive, and unpacks it to the extension dir.
+func fetchArchive(origin string, extensionDir string) (Metadata, error) {
+	resp, err := http.Get(origin)
+	if err != nil {
+		return Metadata{}, fmt.Errorf("failed to download extension: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return Metadata{}, fmt.Errorf("failed to download extension: %s", resp.Status)
+	}
+
+	archive, err := os.CreateTemp("", "sunbeam-archive-*")
+	if err != nil {
+		return Metadata{}, err
+	}
+	defer os.Remove(archive.Name())
+	defer archive.Close()
+
+	if _, err := io.Copy(archive, resp.Body); err != nil {
+		return Metadata{}, fmt.Errorf("failed to download extension: %w", err)
+	}
+
+	srcDir, err := replaceSources(extensionDir, func(dir string) (string, error) {
+		switch archiveFormat(origin) {
+		case "tar.gz":
+			if _, err := archive.Seek(0, io.SeekStart); err != nil {
+				return "", err
+			}
+
+			if err := untar(archive, dir); err != nil {
+				return "", fmt.Errorf("failed to unpack archive: %w", err)
+			}
+		case "zip":
+			info, err := archive.Stat()
+			if err != nil {
+				return "", err
+			}
+
+			if err := unzip(archive, info.Size(), dir); err != nil {
+				return "", fmt.Errorf("failed to unpack archive: %w", err)
+			}
+		}
+
+		// archives generated by code forges wrap the sources in a single top-level directory
+		if _, err := os.Stat(filepath.Join(dir, ManifestFilename)); os.IsNotExist(err) {
+			entries, err := os.ReadDir(dir)
+			if err == nil && len(entries) == 1 && entries[0].IsDir() {
+				return filepath.Join(dir, entries[0].Name()), nil
+			}
+		}
+
+		return dir, nil
+	})
+	if err != nil {
+		return Metadata{}, err
+	}
+
+	return writeDirMetadata(origin, srcDir, extensionDir)
+}
+
+// replaceSources fetches the sources of an extension to a temporary directory, then swaps it with the src dir of the extension.
+// fetch returns the root of the extension, inside the directory it was given.
+// The installed sources are left untouched if the fetch fails, or if the fetched sources do not declare an entrypoint.
+func replaceSources(extensionDir string, fetch func(dir string) (string, error)) (string, error) {
+	if err := os.MkdirAll(extensionDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	tempDir, err := os.MkdirTemp(extensionDir, "src-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create directory: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	if err := os.Chmod(tempDir, 0755); err != nil {
+		return "", err
+	}
+
+	root, err := fetch(tempDir)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := DirEntrypoint(root); err != nil {
+		return "", err
+	}
+
+	rel, err := filepath.Rel(tempDir, root)
+	if err != nil {
+		return "", err
+	}
+
+	srcDir := filepath.Join(extensionDir, "src")
+	backupDir := fmt.Sprintf("%s.old", srcDir)
+	if err := os.RemoveAll(backupDir); err != nil {
+		return "", fmt.Errorf("failed to clean extension directory: %w", err)
+	}
+
+	if err := os.Rename(srcDir, backupDir); err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("failed to replace sources: %w", err)
+	}
+
+	if err := os.Rename(tempDir, srcDir); err != nil {
+		// the previous sources are restored
+		_ = os.Rename(backupDir, srcDir)
+		return "", fmt.Errorf("failed to replace sources: %w", err)
+	}
+
+	// the new sources are in place, a leftover backup is removed by the next upgrade
+	_ = os.RemoveAll(backupDir)
+
+	return filepath.Join(srcDir, rel), nil
+}
+
+func writeDirMetadata(origin string, srcDir string, extensionDir string) (Metadata, error) {
+	entrypoint, err := DirEntrypoint(srcDir)
+	if err != nil {
+		return Metadata{}, err
+	}
+
+	if err := os.Chmod(entrypoint, 0755); err != nil {
+		return Metadata{}, fmt.Errorf("failed to chmod entrypoint: %w", err)
+	}
+
+	metadata := Metadata{
+		Type:       ExtensionTypeLocal,
+		Origin:     origin,
+		Entrypoint: entrypoint,
+		Dir:        srcDir,
+	}
+
+	if err := writeMetadata(metadata, extensionDir); err != nil {
+		return Metadata{}, err
+	}
+
+	return metadata, nil
+}
+
+// archivePath joins the name of an archive entry to the target dir, rejecting entries escaping it.
+// Entries are never written through a symlink, so each existing parent of the entry must be a directory.
+func archivePath(target string, name string) (string, error) {
+	path := filepath.Join(target, name)
+	rel, err := filepath.Rel(target, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid path in archive: %s", name)
+	}
+
+	parent := target
+	for _, part := range strings.Split(filepath.Dir(rel), string(filepath.Separator)) {
+		if part == "." {
+			continue
+		}
+
+		parent = filepath.Join(parent, part)
+		info, err := os.Lstat(parent)
+		if os.IsNotExist(err) {
+			break
+		} else if err != nil {
+			return "", err
+		}
+
+		if !info.IsDir() {
+			return "", fmt.Errorf("invalid path in archive: %s", name)
+		}
+	}
+
+	if info, err := os.Lstat(path); err == nil && info.Mode()&fs.ModeSymlink != 0 {
+		return "", fmt.Errorf("invalid path in archive: %s", name)
+	}
+
+	return path, nil
+}
+
+// checkLinkname rejects symlinks which could resolve outside of the target dir.
+// Links may only go up with leading .. elements, before going down to the linked file.
+// Since their parents are directories, a link can only resolve inside the target dir, whatever the links it goes through.
+func checkLinkname(target string, path string, linkname string) error {
+	if filepath.IsAbs(linkname) {
+		return fmt.Errorf("invalid symlink in archive: %s -> %s", path, linkname)
+	}
+
+	up := true
+	for _, part := range strings.Split(filepath.ToSlash(linkname), "/") {
+		if part != ".." {
+			up = false
+		} else if !up {
+			return fmt.Errorf("invalid symlink in archive: %s -> %s", path, linkname)
+		}
+	}
+
+	resolved := filepath.Join(filepath.Dir(path), linkname)
+	if rel, err := filepath.Rel(target, resolved); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("invalid symlink in archive: %s -> %s", path, linkname)
+	}
+
+	return nil
+}
+
+func untar(r io.Reader, target string) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	reader := tar.NewReader(gz)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		path, err := archivePath(target, header.Name)
+		if err != nil {
+			return err
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(path, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := writeFile(path, reader, header.FileInfo().Mode()); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if err := checkLinkname(target, path, header.Linkname); err != nil {
+				return err
+			}
+
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return err
+			}
+
+			if err := os.Symlink(header.Linkname, path); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func unzip(r io.ReaderAt, size int64, target string) error {
+	reader, err := zip.NewReader(r, size)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range reader.File {
+		path, err := archivePath(target, file.Name)
+		if err != nil {
+			return err
+		}
+
+		if file.FileInfo().IsDir() {
+			if err := os.MkdirAll(path, 0755); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if !file.Mode().IsRegular() {
+			continue
+		}
+
+		rc, err := file.Open()
+		if err != nil {
+			return err
+		}
+
+		err = writeFile(path, rc, file.Mode())
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeFile writes an archive entry, its path must be checked by archivePath first.
+func writeFile(path string, r io.Reader, mode fs.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode.Perm()|0600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+func checksumDir(dir string) (string, error) {
+	h := sha256.New()
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		if d.Type()&fs.ModeSymlink != 0 {
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(h, "%s -> %s\n", filepath.ToSlash(rel), target)
+			return nil
+		}
+
+		checksum, err := Checksum(path)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(h, "%s %s\n", filepath.ToSlash(rel), checksum)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
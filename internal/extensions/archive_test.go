@@ -0,0 +1,224 @@
+package extensions
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type archiveEntry struct {
+	name     string
+	linkname string
+	content  string
+}
+
+func tarball(t *testing.T, entries []archiveEntry) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, entry := range entries {
+		header := &tar.Header{Name: entry.name, Mode: 0644, Typeflag: tar.TypeReg, Size: int64(len(entry.content))}
+		if entry.linkname != "" {
+			header = &tar.Header{Name: entry.name, Mode: 0777, Typeflag: tar.TypeSymlink, Linkname: entry.linkname}
+		}
+
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := tw.Write([]byte(entry.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func zipball(t *testing.T, entries []archiveEntry) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, entry := range entries {
+		header := &zip.FileHeader{Name: entry.name}
+		header.SetMode(0644)
+		content := entry.content
+		if entry.linkname != "" {
+			header.SetMode(fs.ModeSymlink | 0777)
+			content = entry.linkname
+		}
+
+		w, err := zw.CreateHeader(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestUnpack(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		entries []archiveEntry
+		// setup prepares the target dir, outside is a directory next to it
+		setup func(t *testing.T, target string, outside string)
+		// tarErr and zipErr report whether unpacking is expected to fail
+		tarErr bool
+		zipErr bool
+		// files are expected inside of the target dir after unpacking
+		files []string
+	}{
+		{
+			name:    "regular files",
+			entries: []archiveEntry{{name: "sunbeam.manifest.json", content: "{}"}, {name: "bin/main.sh", content: "echo"}},
+			files:   []string{"sunbeam.manifest.json", "bin/main.sh"},
+		},
+		{
+			name:    "path traversal",
+			entries: []archiveEntry{{name: "../outside/x", content: "pwned"}},
+			tarErr:  true,
+			zipErr:  true,
+		},
+		{
+			name:    "absolute symlink",
+			entries: []archiveEntry{{name: "link", linkname: "/etc"}},
+			tarErr:  true,
+		},
+		{
+			name:    "symlink escaping the target",
+			entries: []archiveEntry{{name: "a/link", linkname: "../../outside"}},
+			tarErr:  true,
+		},
+		{
+			name: "chained symlinks",
+			entries: []archiveEntry{
+				{name: "a/b", linkname: ".."},
+				{name: "a/b/c", linkname: ".."},
+				{name: "a/b/c/x", content: "pwned"},
+			},
+			tarErr: true,
+			// zip symlinks are skipped, the file is written inside of real directories
+			files: []string{"a/b/c/x"},
+		},
+		{
+			name: "symlink going through another symlink",
+			entries: []archiveEntry{
+				{name: "a", linkname: "x/b/.."},
+				{name: "x/b", linkname: ".."},
+			},
+			tarErr: true,
+		},
+		{
+			name: "write through a symlink",
+			entries: []archiveEntry{
+				{name: "link/x", content: "pwned"},
+			},
+			setup: func(t *testing.T, target string, outside string) {
+				if err := os.Symlink(outside, filepath.Join(target, "link")); err != nil {
+					t.Fatal(err)
+				}
+			},
+			tarErr: true,
+			zipErr: true,
+		},
+		{
+			name: "overwrite a symlink",
+			entries: []archiveEntry{
+				{name: "link", content: "pwned"},
+			},
+			setup: func(t *testing.T, target string, outside string) {
+				if err := os.Symlink(filepath.Join(outside, "x"), filepath.Join(target, "link")); err != nil {
+					t.Fatal(err)
+				}
+			},
+			tarErr: true,
+			zipErr: true,
+		},
+		{
+			name: "symlink inside of the target",
+			entries: []archiveEntry{
+				{name: "pkg/bin.js", content: "console.log()"},
+				{name: "node_modules/.bin/bin", linkname: "../../pkg/bin.js"},
+			},
+			files: []string{"pkg/bin.js"},
+		},
+	} {
+		for _, format := range []string{"tar.gz", "zip"} {
+			t.Run(tc.name+"/"+format, func(t *testing.T) {
+				root := t.TempDir()
+				target, outside := filepath.Join(root, "target"), filepath.Join(root, "outside")
+				for _, dir := range []string{target, outside} {
+					if err := os.Mkdir(dir, 0755); err != nil {
+						t.Fatal(err)
+					}
+				}
+
+				if tc.setup != nil {
+					tc.setup(t, target, outside)
+				}
+
+				var err error
+				wantErr := tc.tarErr
+				if format == "tar.gz" {
+					err = untar(bytes.NewReader(tarball(t, tc.entries)), target)
+				} else {
+					archive := zipball(t, tc.entries)
+					err = unzip(bytes.NewReader(archive), int64(len(archive)), target)
+					wantErr = tc.zipErr
+				}
+
+				if wantErr && err == nil {
+					t.Error("expected an error")
+				} else if !wantErr && err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+
+				entries, err := os.ReadDir(outside)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if len(entries) > 0 {
+					t.Errorf("%d files were written outside of the target", len(entries))
+				}
+
+				if wantErr {
+					return
+				}
+
+				for _, file := range tc.files {
+					info, err := os.Lstat(filepath.Join(target, file))
+					if err != nil {
+						t.Errorf("missing file %s: %v", file, err)
+					} else if !info.Mode().IsRegular() {
+						t.Errorf("%s is not a regular file", file)
+					}
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package extensions
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pomdtr/sunbeam/internal/types"
+	"github.com/pomdtr/sunbeam/internal/utils"
+)
+
+// CacheTTL returns how long the output of a command stays fresh, or 0 if it is not cached.
+// Only list and detail commands are cached, streamed lists are not.
+func (e Extension) CacheTTL(name string) time.Duration {
+	command, ok := e.Command(name)
+	if !ok || command.Cache <= 0 || command.Streams() {
+		return 0
+	}
+
+	switch command.Mode {
+	case types.CommandModeSearch, types.CommandModeFilter, types.CommandModeDetail:
+		return time.Duration(command.Cache) * time.Second
+	default:
+		return 0
+	}
+}
+
+// outputsDir returns the directory caching the outputs of the commands of an origin.
+func outputsDir(origin string) (string, error) {
+	originHash, err := Hash(origin)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(utils.CacheDir(), "outputs", originHash), nil
+}
+
+// RemoveOutputs deletes the cached outputs of the commands of an origin.
+func RemoveOutputs(origin string) error {
+	dir, err := outputsDir(origin)
+	if err != nil {
+		return err
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", dir, err)
+	}
+
+	return nil
+}
+
+// outputPath returns the file caching the output of a command, keyed by its command, params, query and preferences.
+func (e Extension) outputPath(input types.Payload) (string, error) {
+	dir, err := outputsDir(e.Origin)
+	if err != nil {
+		return "", err
+	}
+
+	key, err := json.Marshal(types.Payload{
+		Command:     input.Command,
+		Params:      input.Params,
+		Query:       input.Query,
+		Preferences: input.Preferences,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	h := sha1.New()
+	h.Write(key)
+	return filepath.Join(dir, hex.EncodeToString(h.Sum(nil))+".json"), nil
+}
+
+// CachedOutput returns the last cached output of a command, and how long ago it was cached.
+func (e Extension) CachedOutput(input types.Payload) ([]byte, time.Duration, error) {
+	outputPath, err := e.outputPath(input)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	info, err := os.Stat(outputPath)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	output, err := os.ReadFile(outputPath)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return output, time.Since(info.ModTime()), nil
+}
+
+// CacheOutput stores the output of a command, if the command declares a cache ttl.
+func (e Extension) CacheOutput(input types.Payload, output []byte) error {
+	if e.CacheTTL(input.Command) == 0 {
+		return nil
+	}
+
+	outputPath, err := e.outputPath(input)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	return os.WriteFile(outputPath, output, 0600)
+}
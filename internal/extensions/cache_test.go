@@ -0,0 +1,93 @@
+package extensions
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/pomdtr/sunbeam/internal/types"
+)
+
+var cachedExtension = Extension{
+	Type:   ExtensionTypeLocal,
+	Origin: "https://example.com/cached.sh",
+	Manifest: types.Manifest{
+		Commands: []types.CommandSpec{
+			{Name: "filter", Mode: types.CommandModeFilter, Cache: 60},
+			{Name: "search", Mode: types.CommandModeSearch, Cache: 60},
+			{Name: "detail", Mode: types.CommandModeDetail, Cache: 60},
+			{Name: "stream", Mode: types.CommandModeFilter, Cache: 60, Stream: true},
+			{Name: "silent", Mode: types.CommandModeSilent, Cache: 60},
+			{Name: "tty", Mode: types.CommandModeTTY, Cache: 60},
+			{Name: "uncached", Mode: types.CommandModeFilter},
+		},
+	},
+}
+
+func TestCacheTTL(t *testing.T) {
+	for name, want := range map[string]time.Duration{
+		"filter":   time.Minute,
+		"search":   time.Minute,
+		"detail":   time.Minute,
+		"stream":   0,
+		"silent":   0,
+		"tty":      0,
+		"uncached": 0,
+		"missing":  0,
+	} {
+		if got := cachedExtension.CacheTTL(name); got != want {
+			t.Errorf("CacheTTL(%s) = %s, want %s", name, got, want)
+		}
+	}
+}
+
+func TestCacheOutput(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	first := types.Payload{Command: "filter", Params: map[string]any{"repo": "pomdtr/sunbeam"}}
+	second := types.Payload{Command: "filter", Params: map[string]any{"repo": "pomdtr/sunbeam-github"}}
+
+	if err := cachedExtension.CacheOutput(first, []byte(`{"items": []}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	output, age, err := cachedExtension.CachedOutput(first)
+	if err != nil {
+		t.Fatalf("cached output: %v", err)
+	}
+
+	if string(output) != `{"items": []}` || age > time.Minute {
+		t.Errorf("cached output = %s (%s old), want the stored output", output, age)
+	}
+
+	if _, _, err := cachedExtension.CachedOutput(second); !os.IsNotExist(err) {
+		t.Errorf("cached output of other params: err = %v, want not exist", err)
+	}
+
+	silent := types.Payload{Command: "silent"}
+	if err := cachedExtension.CacheOutput(silent, []byte("null")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := cachedExtension.CachedOutput(silent); !os.IsNotExist(err) {
+		t.Errorf("cached output of an uncached command: err = %v, want not exist", err)
+	}
+
+	other := cachedExtension
+	other.Origin = "https://example.com/other.sh"
+	if err := other.CacheOutput(first, []byte(`{"items": []}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := RemoveOutputs(cachedExtension.Origin); err != nil {
+		t.Fatalf("remove outputs: %v", err)
+	}
+
+	if _, _, err := cachedExtension.CachedOutput(first); !os.IsNotExist(err) {
+		t.Errorf("cached output after removal: err = %v, want not exist", err)
+	}
+
+	if _, _, err := other.CachedOutput(first); err != nil {
+		t.Errorf("cached output of another origin: %v", err)
+	}
+}
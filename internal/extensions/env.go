@@ -0,0 +1,96 @@
+package extensions
+
+import (
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/pomdtr/sunbeam/internal/secrets"
+)
+
+// DefaultDenyEnv lists the patterns of the environment variables hidden from extensions,
+// unless they are allowed by the manifest or the config.
+var DefaultDenyEnv = []string{
+	"*_TOKEN",
+	"*_SECRET",
+	"*_SECRET_KEY",
+	"*_PASSWORD",
+	"*_API_KEY",
+	"*_ACCESS_KEY",
+	"*_PRIVATE_KEY",
+	"*_CREDENTIALS",
+}
+
+// AllowEnv reports whether an environment variable of the current process is passed to the extension.
+// Patterns denied by the config always win, then patterns allowed by the config or the manifest,
+// then the default deny list. Display variables are hidden from sandboxed extensions without clipboard access.
+func (e Extension) AllowEnv(name string) bool {
+	// the clipboard is reached through the display server
+	if permissions := e.Manifest.Permissions; permissions != nil && !permissions.Clipboard && (name == "DISPLAY" || name == "WAYLAND_DISPLAY") {
+		return false
+	}
+
+	if matchEnv(e.Config.DenyEnv, name) {
+		return false
+	}
+
+	if matchEnv(e.Config.AllowEnv, name) || matchEnv(e.Manifest.AllowEnv, name) {
+		return true
+	}
+
+	return !matchEnv(DefaultDenyEnv, name)
+}
+
+// Environ returns the environment extension processes are started with.
+func (e Extension) Environ() []string {
+	return append(environ(e.AllowEnv, e.Config.Env), e.storageEnv()...)
+}
+
+func defaultAllowEnv(name string) bool {
+	return !matchEnv(DefaultDenyEnv, name)
+}
+
+// environ filters the environment of the current process, then applies the overrides of the config.
+// The secrets passphrase decrypts the secrets of every extension, it is never passed, whatever the config says.
+func environ(allow func(string) bool, overrides map[string]string) []string {
+	var env []string
+	for _, entry := range os.Environ() {
+		name, _, _ := strings.Cut(entry, "=")
+		if _, ok := overrides[name]; ok || name == secrets.PassphraseEnv {
+			continue
+		}
+
+		if !allow(name) {
+			continue
+		}
+
+		env = append(env, entry)
+	}
+
+	names := make([]string, 0, len(overrides))
+	for name := range overrides {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if name == secrets.PassphraseEnv {
+			continue
+		}
+
+		env = append(env, name+"="+overrides[name])
+	}
+
+	return append(env, "SUNBEAM=1")
+}
+
+func matchEnv(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,73 @@
+package extensions
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pomdtr/sunbeam/internal/config"
+	"github.com/pomdtr/sunbeam/internal/secrets"
+	"github.com/pomdtr/sunbeam/internal/types"
+)
+
+func TestEnviron(t *testing.T) {
+	t.Setenv(secrets.PassphraseEnv, "passphrase")
+	t.Setenv("GITHUB_TOKEN", "token")
+	t.Setenv("EDITOR", "vim")
+
+	for _, tc := range []struct {
+		name      string
+		extension Extension
+		allowed   []string
+		denied    []string
+	}{
+		{
+			name:    "default deny list",
+			allowed: []string{"EDITOR"},
+			denied:  []string{"GITHUB_TOKEN", secrets.PassphraseEnv},
+		},
+		{
+			name:      "allowed by the manifest",
+			extension: Extension{Manifest: types.Manifest{AllowEnv: []string{"GITHUB_TOKEN", "SUNBEAM_*"}}},
+			allowed:   []string{"GITHUB_TOKEN"},
+			denied:    []string{secrets.PassphraseEnv},
+		},
+		{
+			name:      "allowed by the config",
+			extension: Extension{Config: config.ExtensionConfig{AllowEnv: []string{"*"}}},
+			allowed:   []string{"GITHUB_TOKEN", "EDITOR"},
+			denied:    []string{secrets.PassphraseEnv},
+		},
+		{
+			name:      "denied by the config",
+			extension: Extension{Config: config.ExtensionConfig{AllowEnv: []string{"*"}, DenyEnv: []string{"EDITOR"}}},
+			allowed:   []string{"GITHUB_TOKEN"},
+			denied:    []string{"EDITOR", secrets.PassphraseEnv},
+		},
+		{
+			name:      "set by the config",
+			extension: Extension{Config: config.ExtensionConfig{Env: map[string]string{secrets.PassphraseEnv: "leaked", "EDITOR": "nano"}}},
+			allowed:   []string{"EDITOR"},
+			denied:    []string{secrets.PassphraseEnv},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			env := make(map[string]bool)
+			for _, entry := range tc.extension.Environ() {
+				name, _, _ := strings.Cut(entry, "=")
+				env[name] = true
+			}
+
+			for _, name := range tc.allowed {
+				if !env[name] {
+					t.Errorf("%s is not passed to the extension", name)
+				}
+			}
+
+			for _, name := range tc.denied {
+				if env[name] {
+					t.Errorf("%s is passed to the extension", name)
+				}
+			}
+		})
+	}
+}
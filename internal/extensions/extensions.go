@@ -1,22 +1,30 @@
 package extensions
 
 import (
+	"bytes"
 	"context"
 	"crypto/sha1"
+	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"mime"
 	"net/http"
 	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
 	"strings"
+	"time"
 
 	"github.com/acarl005/stripansi"
 	"github.com/pomdtr/sunbeam/internal/config"
 	"github.com/pomdtr/sunbeam/internal/schemas"
+	"github.com/pomdtr/sunbeam/internal/secrets"
 	"github.com/pomdtr/sunbeam/internal/types"
 	"github.com/pomdtr/sunbeam/internal/utils"
 )
@@ -33,7 +41,16 @@ func (e ExtensionMap) List() []Extension {
 
 type Extension struct {
 	Manifest   types.Manifest
-	Entrypoint string `json:"entrypoint"`
+	Type       ExtensionType `json:"type"`
+	Origin     string        `json:"origin"`
+	Entrypoint string        `json:"entrypoint"`
+	Dir        string        `json:"dir,omitempty"`
+
+	// Alias and Config identify the installed extension, they are set by callers once the extension is loaded.
+	Alias  string                 `json:"-"`
+	Config config.ExtensionConfig `json:"-"`
+	// StorageDir replaces the data and cache directories of the alias, the test command points it to a temporary directory.
+	StorageDir string `json:"-"`
 }
 
 type Preferences map[string]any
@@ -42,6 +59,27 @@ type Metadata struct {
 	Type       ExtensionType `json:"type"`
 	Origin     string        `json:"origin"`
 	Entrypoint string        `json:"entrypoint"`
+	Dir        string        `json:"dir,omitempty"`
+	// Sha256 is the checksum of the sources, computed once they are fetched
+	Sha256 string `json:"sha256,omitempty"`
+	// Fingerprint describes the size and modification time of the sources the checksum was computed from
+	Fingerprint string `json:"fingerprint,omitempty"`
+}
+
+func (m Metadata) workDir() string {
+	if m.Dir != "" {
+		return m.Dir
+	}
+
+	return filepath.Dir(m.Entrypoint)
+}
+
+func (m Metadata) checksumPath() string {
+	if m.Dir != "" {
+		return m.Dir
+	}
+
+	return m.Entrypoint
 }
 
 type ExtensionType string
@@ -51,6 +89,15 @@ const (
 	ExtensionTypeHttp  ExtensionType = "http"
 )
 
+// WorkDir returns the directory commands are run in.
+func (e Extension) WorkDir() string {
+	if e.Dir != "" {
+		return e.Dir
+	}
+
+	return filepath.Dir(e.Entrypoint)
+}
+
 func (e Extension) Command(name string) (types.CommandSpec, bool) {
 	for _, command := range e.Manifest.Commands {
 		if command.Name == name {
@@ -60,12 +107,82 @@ func (e Extension) Command(name string) (types.CommandSpec, bool) {
 	return types.CommandSpec{}, false
 }
 
+// CurrentPlatform returns the platform sunbeam is running on, using the manifest naming.
+func CurrentPlatform() types.Platfom {
+	if runtime.GOOS == "darwin" {
+		return types.PlatformMac
+	}
+
+	return types.Platfom(runtime.GOOS)
+}
+
+func supportsPlatform(platforms []types.Platfom) bool {
+	if len(platforms) == 0 {
+		return true
+	}
+
+	for _, platform := range platforms {
+		if platform == CurrentPlatform() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsSupported reports whether the extension can run on the current platform.
+func (e Extension) IsSupported() bool {
+	return supportsPlatform(e.Manifest.Platforms)
+}
+
+// SupportsCommand reports whether both the extension and the command can run on the current platform.
+func (e Extension) SupportsCommand(command types.CommandSpec) bool {
+	return e.IsSupported() && supportsPlatform(command.Platforms)
+}
+
+type MissingRequirementsError struct {
+	Requirements []types.Requirement
+}
+
+func (e *MissingRequirementsError) Error() string {
+	var lines []string
+	for _, requirement := range e.Requirements {
+		if requirement.Link != "" {
+			lines = append(lines, fmt.Sprintf("  - %s: %s", requirement.Name, requirement.Link))
+		} else {
+			lines = append(lines, fmt.Sprintf("  - %s", requirement.Name))
+		}
+	}
+
+	return fmt.Sprintf("missing requirements:\n\n%s", strings.Join(lines, "\n"))
+}
+
+// CheckRequirements looks up the binaries required by the extension in the PATH.
+func (e Extension) CheckRequirements() error {
+	if e.Type == ExtensionTypeHttp {
+		return nil
+	}
+
+	var missing []types.Requirement
+	for _, requirement := range e.Manifest.Requirements {
+		if _, err := exec.LookPath(requirement.Name); err != nil {
+			missing = append(missing, requirement)
+		}
+	}
+
+	if len(missing) > 0 {
+		return &MissingRequirementsError{Requirements: missing}
+	}
+
+	return nil
+}
+
 func (e Extension) RootItems() []types.RootItem {
 	var items []types.RootItem
 	if e.Manifest.Root != nil {
 		for _, name := range e.Manifest.Root {
 			command, ok := e.Command(name)
-			if !ok {
+			if !ok || !e.SupportsCommand(command) {
 				continue
 			}
 
@@ -78,7 +195,7 @@ func (e Extension) RootItems() []types.RootItem {
 	}
 
 	for _, command := range e.Manifest.Commands {
-		if command.Hidden {
+		if command.Hidden || !e.SupportsCommand(command) {
 			continue
 		}
 
@@ -101,8 +218,82 @@ func (e Extension) Run(input types.Payload) error {
 	return err
 }
 
-func (ext Extension) Output(input types.Payload) ([]byte, error) {
-	cmd, err := ext.Cmd(input)
+func (e Extension) Output(input types.Payload) ([]byte, error) {
+	return e.OutputContext(context.Background(), input)
+}
+
+// OutputContext runs a command and returns its output.
+func (e Extension) OutputContext(ctx context.Context, input types.Payload) ([]byte, error) {
+	return e.output(ctx, MethodRun, input)
+}
+
+// ReloadContext runs a command whose output is already displayed.
+// Persistent extensions receive it as a reload request, other extensions are spawned again.
+func (e Extension) ReloadContext(ctx context.Context, input types.Payload) ([]byte, error) {
+	return e.output(ctx, MethodReload, input)
+}
+
+// TimeoutError is returned when a command does not complete before its timeout.
+type TimeoutError struct {
+	Command string
+	Timeout time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("command %s timed out after %s", e.Command, e.Timeout)
+}
+
+// CommandTimeout returns the timeout of a command, or 0 if the command can run indefinitely.
+// The timeout set in the config takes precedence over the one declared in the manifest.
+func (e Extension) CommandTimeout(name string) time.Duration {
+	if e.Config.Timeout > 0 {
+		return time.Duration(e.Config.Timeout) * time.Second
+	}
+
+	if command, ok := e.Command(name); ok && command.Timeout > 0 {
+		return time.Duration(command.Timeout) * time.Second
+	}
+
+	return 0
+}
+
+func (e Extension) output(ctx context.Context, method string, input types.Payload) ([]byte, error) {
+	timeout := e.CommandTimeout(input.Command)
+	if timeout == 0 {
+		return e.call(ctx, method, input)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	output, err := e.call(ctx, method, input)
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return nil, &TimeoutError{Command: input.Command, Timeout: timeout}
+	}
+
+	return output, err
+}
+
+func (e Extension) call(ctx context.Context, method string, input types.Payload) ([]byte, error) {
+	if e.Type == ExtensionTypeHttp {
+		return e.post(ctx, input)
+	}
+
+	if e.Manifest.Persistent {
+		payload, err := e.preparePayload(input)
+		if err != nil {
+			return nil, err
+		}
+
+		process, err := e.process()
+		if err != nil {
+			return nil, err
+		}
+
+		return process.Call(ctx, method, payload)
+	}
+
+	cmd, err := e.CmdContext(ctx, input)
 	if err != nil {
 		return nil, err
 	}
@@ -110,6 +301,8 @@ func (ext Extension) Output(input types.Payload) ([]byte, error) {
 	var exitErr *exec.ExitError
 	if output, err := cmd.Output(); err == nil {
 		return output, nil
+	} else if ctx.Err() != nil {
+		return nil, ctx.Err()
 	} else if errors.As(err, &exitErr) {
 		return nil, fmt.Errorf("command failed: %s", stripansi.Strip(string(exitErr.Stderr)))
 	} else {
@@ -122,17 +315,57 @@ func (e Extension) Cmd(input types.Payload) (*exec.Cmd, error) {
 }
 
 func (e Extension) CmdContext(ctx context.Context, input types.Payload) (*exec.Cmd, error) {
-	if input.Preferences == nil {
-		input.Preferences = make(map[string]any)
+	if e.Type == ExtensionTypeHttp {
+		return nil, fmt.Errorf("http extensions can only return lists and details")
+	}
+
+	input, err := e.preparePayload(input)
+	if err != nil {
+		return nil, err
+	}
+
+	inputBytes, err := json.Marshal(input)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := e.createStorageDirs(); err != nil {
+		return nil, err
+	}
+
+	cmd, err := e.command(ctx, string(inputBytes))
+	if err != nil {
+		return nil, err
+	}
+	cmd.Dir = e.WorkDir()
+	cmd.Env = e.Environ()
+	// do not wait for the children of a killed extension to release its output
+	cmd.WaitDelay = 1 * time.Second
+	return cmd, nil
+}
+
+func (e Extension) preparePayload(input types.Payload) (types.Payload, error) {
+	if err := e.CheckRequirements(); err != nil {
+		return types.Payload{}, err
 	}
 
+	if err := e.CheckPermissions(); err != nil {
+		return types.Payload{}, err
+	}
+
+	preferences, err := secrets.Reveal(input.Preferences)
+	if err != nil {
+		return types.Payload{}, err
+	}
+	input.Preferences = preferences
+
 	for _, spec := range e.Manifest.Preferences {
 		if _, ok := input.Preferences[spec.Name]; ok {
 			continue
 		}
 
 		if spec.Required {
-			return nil, fmt.Errorf("missing required preference %s", spec.Name)
+			return types.Payload{}, fmt.Errorf("missing required preference %s", spec.Name)
 		}
 
 		input.Preferences[spec.Name] = spec.Default
@@ -140,12 +373,18 @@ func (e Extension) CmdContext(ctx context.Context, input types.Payload) (*exec.C
 
 	command, ok := e.Command(input.Command)
 	if !ok {
-		return nil, fmt.Errorf("command %s not found", input.Command)
+		return types.Payload{}, fmt.Errorf("command %s not found", input.Command)
+	}
+
+	if !e.SupportsCommand(command) {
+		return types.Payload{}, fmt.Errorf("command %s is not supported on %s", input.Command, CurrentPlatform())
 	}
 
-	if input.Params == nil {
-		input.Params = make(map[string]any)
+	params := make(map[string]any)
+	for name, value := range input.Params {
+		params[name] = value
 	}
+	input.Params = params
 
 	for _, spec := range command.Params {
 		if _, ok := input.Params[spec.Name]; ok {
@@ -153,7 +392,7 @@ func (e Extension) CmdContext(ctx context.Context, input types.Payload) (*exec.C
 		}
 
 		if spec.Required {
-			return nil, fmt.Errorf("missing required parameter %s", spec.Name)
+			return types.Payload{}, fmt.Errorf("missing required parameter %s", spec.Name)
 		}
 
 		input.Params[spec.Name] = spec.Default
@@ -161,20 +400,51 @@ func (e Extension) CmdContext(ctx context.Context, input types.Payload) (*exec.C
 
 	cwd, err := os.Getwd()
 	if err != nil {
-		return nil, err
+		return types.Payload{}, err
 	}
 	input.Cwd = cwd
 
-	inputBytes, err := json.Marshal(input)
+	return input, nil
+}
+
+// post sends the payload to the origin of an http extension, and returns the response body.
+func (e Extension) post(ctx context.Context, input types.Payload) ([]byte, error) {
+	payload, err := e.preparePayload(input)
 	if err != nil {
 		return nil, err
 	}
 
-	cmd := exec.CommandContext(ctx, e.Entrypoint, string(inputBytes))
-	cmd.Dir = filepath.Dir(e.Entrypoint)
-	cmd.Env = os.Environ()
-	cmd.Env = append(cmd.Env, "SUNBEAM=1")
-	return cmd, nil
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.Origin, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	output, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("command failed: %s: %s", resp.Status, strings.TrimSpace(string(output)))
+	}
+
+	return output, nil
 }
 
 func Hash(origin string) (string, error) {
@@ -193,195 +463,473 @@ func Hash(origin string) (string, error) {
 }
 
 func IsRemote(origin string) bool {
-	return strings.HasPrefix(origin, "http://") || strings.HasPrefix(origin, "https://")
+	return strings.HasPrefix(origin, "http://") || strings.HasPrefix(origin, "https://") || strings.HasPrefix(origin, "git+")
 }
 
-func DownloadEntrypoint(origin string, target string) error {
-	resp, err := http.Get(origin)
+func LoadEntrypoint(origin string) (string, error) {
+	entrypoint := origin
+	if strings.HasPrefix(entrypoint, "~") {
+		entrypoint = strings.Replace(entrypoint, "~", os.Getenv("HOME"), 1)
+	} else if !filepath.IsAbs(entrypoint) {
+		entrypoint = filepath.Join(filepath.Dir(config.Path), entrypoint)
+	}
+
+	return filepath.Abs(entrypoint)
+}
+
+func LoadExtension(origin string) (Extension, error) {
+	hash, err := Hash(origin)
 	if err != nil {
-		return fmt.Errorf("failed to download extension: %w", err)
+		return Extension{}, err
 	}
-	defer resp.Body.Close()
+	extensionDir := filepath.Join(utils.CacheDir(), "extensions", hash)
+	manifestPath := filepath.Join(extensionDir, "manifest.json")
 
-	if resp.StatusCode != 200 {
-		return fmt.Errorf("failed to download extension: %s", resp.Status)
+	var entrypoint, dir string
+	if IsRemote(origin) {
+		metadata, err := loadMetadata(extensionDir)
+		if err != nil {
+			m, err := Fetch(origin, extensionDir)
+			if err != nil {
+				return Extension{}, err
+			}
+			metadata = m
+		}
+
+		if metadata.Type == ExtensionTypeHttp {
+			manifest, err := readManifest(manifestPath)
+			if err != nil {
+				return Extension{}, err
+			}
+
+			return Extension{
+				Manifest:   manifest,
+				Type:       ExtensionTypeHttp,
+				Origin:     origin,
+				Entrypoint: origin,
+			}, nil
+		}
+
+		if err := verifyChecksum(origin, metadata, extensionDir); err != nil {
+			return Extension{}, err
+		}
+
+		entrypoint = metadata.Entrypoint
+		dir = metadata.Dir
+	} else {
+		e, err := LoadEntrypoint(origin)
+		if err != nil {
+			return Extension{}, err
+		}
+
+		if info, err := os.Stat(e); err == nil && info.IsDir() {
+			dir = e
+			if e, err = DirEntrypoint(dir); err != nil {
+				return Extension{}, err
+			}
+		}
+		entrypoint = e
+	}
+
+	extension := Extension{
+		Type:       ExtensionTypeLocal,
+		Origin:     origin,
+		Entrypoint: entrypoint,
+		Dir:        dir,
 	}
 
-	f, err := os.Create(target)
+	modTime, err := sourceModTime(extension.Entrypoint, extension.Dir)
 	if err != nil {
-		return fmt.Errorf("failed to create entrypoint: %w", err)
+		return Extension{}, err
 	}
 
-	if _, err := f.ReadFrom(resp.Body); err != nil {
-		return fmt.Errorf("failed to write entrypoint: %w", err)
+	manifestInfo, err := os.Stat(manifestPath)
+	if err != nil || modTime.After(manifestInfo.ModTime()) {
+		manifest, err := cacheManifest(extension.Entrypoint, extension.WorkDir(), manifestPath)
+		if err != nil {
+			return Extension{}, err
+		}
+
+		extension.Manifest = manifest
+		return extension, nil
 	}
 
-	if err := f.Close(); err != nil {
-		return fmt.Errorf("failed to close entrypoint: %w", err)
+	manifest, err := readManifest(manifestPath)
+	if err != nil {
+		return Extension{}, err
 	}
 
-	return nil
+	extension.Manifest = manifest
+	return extension, nil
 }
 
-func LoadEntrypoint(origin string, extensionDir string) (string, error) {
-	if IsRemote(origin) {
-		originUrl, err := url.Parse(origin)
+// Checksum returns the hex-encoded sha256 of a file.
+// The checksum of a directory covers the path and content of each of its files.
+func Checksum(path string) (string, error) {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return checksumDir(path)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// fingerprint returns the hex-encoded sha256 of the path, size, mode and modification time of the files under a path.
+// It changes whenever the files are modified, without reading their content.
+func fingerprint(path string) (string, error) {
+	h := sha256.New()
+	err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
 		if err != nil {
-			return "", fmt.Errorf("failed to parse origin: %w", err)
+			return err
 		}
 
-		entrypoint := filepath.Join(extensionDir, filepath.Base(originUrl.Path))
-		if _, err := os.Stat(entrypoint); err == nil {
-			return entrypoint, nil
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
 		}
 
-		if err := os.MkdirAll(extensionDir, 0755); err != nil {
-			return "", fmt.Errorf("failed to create directory: %w", err)
+		info, err := d.Info()
+		if err != nil {
+			return err
 		}
 
-		if err := DownloadEntrypoint(origin, entrypoint); err != nil {
-			return "", err
+		rel, err := filepath.Rel(path, p)
+		if err != nil {
+			return err
 		}
 
-		if err := os.Chmod(entrypoint, 0755); err != nil {
-			return "", fmt.Errorf("failed to chmod entrypoint: %w", err)
-		}
+		fmt.Fprintf(h, "%s %d %s %d\n", filepath.ToSlash(rel), info.Size(), info.Mode(), info.ModTime().UnixNano())
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
 
-		return entrypoint, nil
+// Install fetches a remote extension, and records its checksum in the lockfile.
+// Origins already in the lockfile are not fetched again, they must match the recorded checksum.
+func Install(origin string) (Extension, error) {
+	if !IsRemote(origin) {
+		return LoadExtension(origin)
 	}
 
-	entrypoint := origin
-	if strings.HasPrefix(entrypoint, "~") {
-		entrypoint = strings.Replace(entrypoint, "~", os.Getenv("HOME"), 1)
-	} else if !filepath.IsAbs(entrypoint) {
-		entrypoint = filepath.Join(filepath.Dir(config.Path), entrypoint)
+	lockfile, err := config.LoadLockfile(config.LockPath())
+	if err != nil {
+		return Extension{}, err
 	}
 
-	return filepath.Abs(entrypoint)
-}
+	if _, ok := lockfile.Extensions[origin]; ok {
+		return LoadExtension(origin)
+	}
 
-func LoadExtension(origin string) (Extension, error) {
-	hash, err := Hash(origin)
+	extensionDir, err := extensionDir(origin)
 	if err != nil {
 		return Extension{}, err
 	}
-	extensionDir := filepath.Join(utils.CacheDir(), "extensions", hash)
-	entrypoint, err := LoadEntrypoint(origin, extensionDir)
+
+	metadata, err := Fetch(origin, extensionDir)
 	if err != nil {
 		return Extension{}, err
 	}
 
-	entrypointInfo, err := os.Stat(entrypoint)
-	if err != nil {
+	if err := pinChecksum(origin, metadata, extensionDir); err != nil {
 		return Extension{}, err
 	}
 
-	manifestPath := filepath.Join(extensionDir, "manifest.json")
-	manifestInfo, err := os.Stat(manifestPath)
-	if err != nil || entrypointInfo.ModTime().After(manifestInfo.ModTime()) {
-		manifest, err := cacheManifest(entrypoint, manifestPath)
-		if err != nil {
-			return Extension{}, err
-		}
+	return LoadExtension(origin)
+}
 
-		return Extension{
-			Manifest:   manifest,
-			Entrypoint: entrypoint,
-		}, nil
+// pinChecksum records the checksum of freshly fetched sources in the metadata of the extension and in the lockfile.
+func pinChecksum(origin string, metadata Metadata, extensionDir string) error {
+	if metadata.Type == ExtensionTypeHttp {
+		return nil
 	}
 
-	manifestBytes, err := os.ReadFile(manifestPath)
+	metadata, err := updateChecksum(metadata, extensionDir)
 	if err != nil {
-		return Extension{}, fmt.Errorf("failed to read manifest: %w", err)
+		return err
 	}
 
-	var manifest types.Manifest
-	if err := json.Unmarshal(manifestBytes, &manifest); err != nil {
-		return Extension{}, fmt.Errorf("failed to decode manifest: %w", err)
+	lockfile, err := config.LoadLockfile(config.LockPath())
+	if err != nil {
+		return err
 	}
 
-	return Extension{
-		Manifest:   manifest,
-		Entrypoint: entrypoint,
-	}, nil
+	lockfile.Extensions[origin] = config.LockEntry{Sha256: metadata.Sha256}
+	return lockfile.Save()
 }
 
-func cacheManifest(entrypoint string, manifestPath string) (types.Manifest, error) {
-	manifest, err := ExtractManifest(entrypoint)
+// verifyChecksum compares the checksum of the sources of a remote extension with the one recorded in the lockfile.
+// The checksum is cached in the metadata of the extension, and computed again once the sources are modified.
+func verifyChecksum(origin string, metadata Metadata, extensionDir string) error {
+	// extensions installed before checksums were recorded are pinned on their first load
+	migrate := metadata.Sha256 == ""
+
+	metadata, err := updateChecksum(metadata, extensionDir)
 	if err != nil {
-		return types.Manifest{}, fmt.Errorf("failed to extract manifest: %w", err)
+		return err
 	}
 
-	if err := os.MkdirAll(filepath.Dir(manifestPath), 0755); err != nil {
-		return types.Manifest{}, fmt.Errorf("failed to create directory: %w", err)
+	lockfile, err := config.LoadLockfile(config.LockPath())
+	if err != nil {
+		return err
 	}
 
-	f, err := os.Create(manifestPath)
+	entry, ok := lockfile.Extensions[origin]
+	if !ok && migrate {
+		lockfile.Extensions[origin] = config.LockEntry{Sha256: metadata.Sha256}
+		return lockfile.Save()
+	}
+
+	if !ok {
+		return fmt.Errorf("%s is missing from %s, upgrade the extension to record its checksum", origin, config.LockPath())
+	}
+
+	if entry.Sha256 != metadata.Sha256 {
+		return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", origin, entry.Sha256, metadata.Sha256)
+	}
+
+	return nil
+}
+
+// updateChecksum computes the checksum of the sources if they changed since it was cached in the metadata.
+func updateChecksum(metadata Metadata, extensionDir string) (Metadata, error) {
+	current, err := fingerprint(metadata.checksumPath())
 	if err != nil {
-		return types.Manifest{}, fmt.Errorf("failed to create manifest: %w", err)
+		return Metadata{}, fmt.Errorf("failed to compute checksum: %w", err)
 	}
-	defer f.Close()
 
-	if err := json.NewEncoder(f).Encode(manifest); err != nil {
-		return types.Manifest{}, fmt.Errorf("failed to write manifest: %w", err)
+	if metadata.Sha256 != "" && metadata.Fingerprint == current {
+		return metadata, nil
 	}
 
-	return manifest, nil
+	checksum, err := Checksum(metadata.checksumPath())
+	if err != nil {
+		return Metadata{}, fmt.Errorf("failed to compute checksum: %w", err)
+	}
+
+	metadata.Sha256, metadata.Fingerprint = checksum, current
+	if err := writeMetadata(metadata, extensionDir); err != nil {
+		return Metadata{}, err
+	}
+
+	return metadata, nil
 }
 
-func Upgrade(extensionConfig config.ExtensionConfig) error {
-	hash, err := Hash(extensionConfig.Origin)
+// Fetch downloads a remote extension to the extension dir.
+// Git repositories and archives are unpacked, origins serving a JSON manifest are http extensions,
+// and other origins are downloaded as scripts.
+func Fetch(origin string, extensionDir string) (Metadata, error) {
+	if IsGitRepository(origin) {
+		return fetchRepository(origin, extensionDir)
+	}
+
+	if IsArchive(origin) {
+		return fetchArchive(origin, extensionDir)
+	}
+
+	originUrl, err := url.Parse(origin)
 	if err != nil {
-		return err
+		return Metadata{}, fmt.Errorf("failed to parse origin: %w", err)
 	}
 
-	extensionDir := filepath.Join(utils.CacheDir(), "extensions", hash)
-	manifestPath := filepath.Join(extensionDir, "manifest.json")
-	if IsRemote(extensionConfig.Origin) {
-		originUrl, err := url.Parse(extensionConfig.Origin)
+	resp, err := http.Get(origin)
+	if err != nil {
+		return Metadata{}, fmt.Errorf("failed to download extension: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return Metadata{}, fmt.Errorf("failed to download extension: %s", resp.Status)
+	}
+
+	if err := os.MkdirAll(extensionDir, 0755); err != nil {
+		return Metadata{}, fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	var metadata Metadata
+	if isJSON(resp.Header.Get("Content-Type")) {
+		manifestBytes, err := io.ReadAll(resp.Body)
 		if err != nil {
-			return fmt.Errorf("failed to parse origin: %w", err)
+			return Metadata{}, fmt.Errorf("failed to download manifest: %w", err)
 		}
 
+		manifest, err := decodeManifest(manifestBytes)
+		if err != nil {
+			return Metadata{}, err
+		}
+
+		if err := writeManifest(manifest, filepath.Join(extensionDir, "manifest.json")); err != nil {
+			return Metadata{}, err
+		}
+
+		metadata = Metadata{
+			Type:       ExtensionTypeHttp,
+			Origin:     origin,
+			Entrypoint: origin,
+		}
+	} else {
 		entrypoint := filepath.Join(extensionDir, filepath.Base(originUrl.Path))
-		if err := DownloadEntrypoint(extensionConfig.Origin, entrypoint); err != nil {
-			return err
+		f, err := os.Create(entrypoint)
+		if err != nil {
+			return Metadata{}, fmt.Errorf("failed to create entrypoint: %w", err)
 		}
 
-		if _, err := cacheManifest(entrypoint, manifestPath); err != nil {
-			return err
+		if _, err := f.ReadFrom(resp.Body); err != nil {
+			return Metadata{}, fmt.Errorf("failed to write entrypoint: %w", err)
 		}
 
-		return nil
+		if err := f.Close(); err != nil {
+			return Metadata{}, fmt.Errorf("failed to close entrypoint: %w", err)
+		}
+
+		if err := os.Chmod(entrypoint, 0755); err != nil {
+			return Metadata{}, fmt.Errorf("failed to chmod entrypoint: %w", err)
+		}
+
+		metadata = Metadata{
+			Type:       ExtensionTypeLocal,
+			Origin:     origin,
+			Entrypoint: entrypoint,
+		}
 	}
 
-	entrypoint := extensionConfig.Origin
-	if strings.HasPrefix(entrypoint, "~") {
-		entrypoint = strings.Replace(entrypoint, "~", os.Getenv("HOME"), 1)
-	} else if !filepath.IsAbs(entrypoint) {
-		entrypoint = filepath.Join(filepath.Dir(config.Path), entrypoint)
+	if err := writeMetadata(metadata, extensionDir); err != nil {
+		return Metadata{}, err
 	}
 
-	if _, err := cacheManifest(entrypoint, manifestPath); err != nil {
+	return metadata, nil
+}
+
+func writeMetadata(metadata Metadata, extensionDir string) error {
+	metadataBytes, err := json.Marshal(metadata)
+	if err != nil {
 		return err
 	}
+
+	if err := os.WriteFile(filepath.Join(extensionDir, "metadata.json"), metadataBytes, 0644); err != nil {
+		return fmt.Errorf("failed to write metadata: %w", err)
+	}
+
+	return nil
+}
+
+func loadMetadata(extensionDir string) (Metadata, error) {
+	metadataBytes, err := os.ReadFile(filepath.Join(extensionDir, "metadata.json"))
+	if err != nil {
+		return Metadata{}, err
+	}
+
+	var metadata Metadata
+	if err := json.Unmarshal(metadataBytes, &metadata); err != nil {
+		return Metadata{}, fmt.Errorf("failed to decode metadata: %w", err)
+	}
+
+	return metadata, nil
+}
+
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+func readManifest(manifestPath string) (types.Manifest, error) {
+	manifestBytes, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return types.Manifest{}, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	var manifest types.Manifest
+	if err := json.Unmarshal(manifestBytes, &manifest); err != nil {
+		return types.Manifest{}, fmt.Errorf("failed to decode manifest: %w", err)
+	}
+
+	return manifest, nil
+}
+
+func writeManifest(manifest types.Manifest, manifestPath string) error {
+	if err := os.MkdirAll(filepath.Dir(manifestPath), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	f, err := os.Create(manifestPath)
+	if err != nil {
+		return fmt.Errorf("failed to create manifest: %w", err)
+	}
+	defer f.Close()
+
+	if err := json.NewEncoder(f).Encode(manifest); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+
 	return nil
 }
 
+func cacheManifest(entrypoint string, dir string, manifestPath string) (types.Manifest, error) {
+	manifest, err := extractManifest(entrypoint, dir)
+	if err != nil {
+		return types.Manifest{}, fmt.Errorf("failed to extract manifest: %w", err)
+	}
+
+	if err := writeManifest(manifest, manifestPath); err != nil {
+		return types.Manifest{}, err
+	}
+
+	return manifest, nil
+}
+
+// ExtractManifest reads the manifest of a script or of an extension directory.
+// Static manifests are preferred, the entrypoint is only executed as a fallback.
 func ExtractManifest(entrypoint string) (types.Manifest, error) {
 	entrypoint, err := filepath.Abs(entrypoint)
 	if err != nil {
 		return types.Manifest{}, err
 	}
 
+	if info, err := os.Stat(entrypoint); err == nil && info.IsDir() {
+		dir := entrypoint
+		if entrypoint, err = DirEntrypoint(dir); err != nil {
+			return types.Manifest{}, err
+		}
+
+		return extractManifest(entrypoint, dir)
+	}
+
+	return extractManifest(entrypoint, filepath.Dir(entrypoint))
+}
+
+func extractManifest(entrypoint string, dir string) (types.Manifest, error) {
+	if manifest, ok, err := staticManifest(entrypoint, dir); err != nil {
+		return types.Manifest{}, err
+	} else if ok {
+		return manifest, nil
+	}
+
 	if err := os.Chmod(entrypoint, 0755); err != nil {
 		return types.Manifest{}, err
 	}
 
-	cmd := exec.Command(entrypoint)
-	cmd.Dir = filepath.Dir(entrypoint)
-	cmd.Env = os.Environ()
-	cmd.Env = append(cmd.Env, "SUNBEAM=1")
+	cmd, err := manifestCommand(entrypoint, dir)
+	if err != nil {
+		return types.Manifest{}, err
+	}
+	cmd.Dir = dir
+	cmd.Env = environ(defaultAllowEnv, nil)
 
 	manifestBytes, err := cmd.Output()
 	if err != nil {
@@ -392,6 +940,10 @@ func ExtractManifest(entrypoint string) (types.Manifest, error) {
 		return types.Manifest{}, err
 	}
 
+	return decodeManifest(manifestBytes)
+}
+
+func decodeManifest(manifestBytes []byte) (types.Manifest, error) {
 	if err := schemas.ValidateManifest(manifestBytes); err != nil {
 		return types.Manifest{}, err
 	}
@@ -403,3 +955,23 @@ func ExtractManifest(entrypoint string) (types.Manifest, error) {
 
 	return manifest, nil
 }
+
+// FetchManifest downloads the manifest of an http extension.
+func FetchManifest(origin string) (types.Manifest, error) {
+	resp, err := http.Get(origin)
+	if err != nil {
+		return types.Manifest{}, fmt.Errorf("failed to fetch manifest: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return types.Manifest{}, fmt.Errorf("failed to fetch manifest: %s", resp.Status)
+	}
+
+	manifestBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return types.Manifest{}, fmt.Errorf("failed to fetch manifest: %w", err)
+	}
+
+	return decodeManifest(manifestBytes)
+}
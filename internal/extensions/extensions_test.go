@@ -0,0 +1,231 @@
+package extensions
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pomdtr/sunbeam/internal/config"
+	"github.com/pomdtr/sunbeam/internal/types"
+)
+
+func TestHttpExtension(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.Write([]byte(`{"title": "Test", "root": ["hello"], "commands": [{"name": "hello", "title": "Hello", "mode": "detail"}]}`))
+			return
+		}
+
+		var payload types.Payload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		json.NewEncoder(w).Encode(map[string]any{
+			"contentType": r.Header.Get("Content-Type"),
+			"command":     payload.Command,
+			"params":      payload.Params,
+		})
+	})
+	mux.HandleFunc("/broken", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	extensionDir := t.TempDir()
+	metadata, err := Fetch(server.URL, extensionDir)
+	if err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+
+	if metadata.Type != ExtensionTypeHttp || metadata.Entrypoint != server.URL {
+		t.Fatalf("metadata = %+v, want an http extension served by %s", metadata, server.URL)
+	}
+
+	manifest, err := readManifest(filepath.Join(extensionDir, "manifest.json"))
+	if err != nil {
+		t.Fatalf("read manifest: %v", err)
+	}
+
+	extension := Extension{Manifest: manifest, Type: metadata.Type, Origin: metadata.Origin, Entrypoint: metadata.Entrypoint}
+	output, err := extension.Output(types.Payload{Command: "hello", Params: map[string]any{"name": "sunbeam"}})
+	if err != nil {
+		t.Fatalf("output: %v", err)
+	}
+
+	var request struct {
+		ContentType string         `json:"contentType"`
+		Command     string         `json:"command"`
+		Params      map[string]any `json:"params"`
+	}
+	if err := json.Unmarshal(output, &request); err != nil {
+		t.Fatalf("decode output: %v", err)
+	}
+
+	if request.ContentType != "application/json" {
+		t.Errorf("content type = %q, want application/json", request.ContentType)
+	}
+
+	if request.Command != "hello" || request.Params["name"] != "sunbeam" {
+		t.Errorf("payload = %+v, want the hello command and its params", request)
+	}
+
+	broken := extension
+	broken.Origin, broken.Entrypoint = server.URL+"/broken", server.URL+"/broken"
+	if _, err := broken.Output(types.Payload{Command: "hello"}); err == nil || !strings.Contains(err.Error(), "500") || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("output error = %v, want the status and body of the response", err)
+	}
+
+	if _, err := Fetch(broken.Origin, t.TempDir()); err == nil {
+		t.Error("fetch: expected an error for a 500 response")
+	}
+
+	if _, err := FetchManifest(broken.Origin); err == nil {
+		t.Error("fetch manifest: expected an error for a 500 response")
+	}
+}
+
+func TestVerifyChecksum(t *testing.T) {
+	const origin = "https://example.com/script.sh"
+
+	for _, tc := range []struct {
+		name    string
+		prepare func(t *testing.T, metadata Metadata, extensionDir string) Metadata
+		err     string
+	}{
+		{
+			name: "pinned",
+			prepare: func(t *testing.T, metadata Metadata, extensionDir string) Metadata {
+				if err := pinChecksum(origin, metadata, extensionDir); err != nil {
+					t.Fatal(err)
+				}
+				return readTestMetadata(t, extensionDir)
+			},
+		},
+		{
+			name: "edited",
+			prepare: func(t *testing.T, metadata Metadata, extensionDir string) Metadata {
+				if err := pinChecksum(origin, metadata, extensionDir); err != nil {
+					t.Fatal(err)
+				}
+
+				if err := os.WriteFile(metadata.Entrypoint, []byte("#!/bin/sh\necho tampered\n"), 0755); err != nil {
+					t.Fatal(err)
+				}
+				return readTestMetadata(t, extensionDir)
+			},
+			err: "checksum mismatch",
+		},
+		{
+			name: "mismatch",
+			prepare: func(t *testing.T, metadata Metadata, extensionDir string) Metadata {
+				writeTestLockfile(t, config.LockEntry{Sha256: "0000"})
+				return metadata
+			},
+			err: "checksum mismatch",
+		},
+		{
+			name: "missing",
+			prepare: func(t *testing.T, metadata Metadata, extensionDir string) Metadata {
+				if err := pinChecksum(origin, metadata, extensionDir); err != nil {
+					t.Fatal(err)
+				}
+
+				if err := os.Remove(config.LockPath()); err != nil {
+					t.Fatal(err)
+				}
+				return readTestMetadata(t, extensionDir)
+			},
+			err: "missing from",
+		},
+		{
+			name: "installed before the lockfile",
+			prepare: func(t *testing.T, metadata Metadata, extensionDir string) Metadata {
+				return metadata
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			setConfigPath(t, filepath.Join(t.TempDir(), "sunbeam.json"))
+
+			extensionDir := t.TempDir()
+			metadata := Metadata{
+				Type:       ExtensionTypeLocal,
+				Origin:     origin,
+				Entrypoint: filepath.Join(extensionDir, "script.sh"),
+			}
+			if err := os.WriteFile(metadata.Entrypoint, []byte("#!/bin/sh\necho hello\n"), 0755); err != nil {
+				t.Fatal(err)
+			}
+
+			metadata = tc.prepare(t, metadata, extensionDir)
+			err := verifyChecksum(origin, metadata, extensionDir)
+			if tc.err == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				lockfile, err := config.LoadLockfile(config.LockPath())
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if lockfile.Extensions[origin].Sha256 != readTestMetadata(t, extensionDir).Sha256 {
+					t.Errorf("lockfile = %+v, want the checksum of the sources", lockfile.Extensions)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("error = %v, want %q", err, tc.err)
+			}
+		})
+	}
+}
+
+func setConfigPath(t *testing.T, path string) {
+	t.Helper()
+
+	previous := config.Path
+	config.Path = path
+	t.Cleanup(func() { config.Path = previous })
+}
+
+func readTestMetadata(t *testing.T, extensionDir string) Metadata {
+	t.Helper()
+
+	bts, err := os.ReadFile(filepath.Join(extensionDir, "metadata.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var metadata Metadata
+	if err := json.Unmarshal(bts, &metadata); err != nil {
+		t.Fatal(err)
+	}
+
+	return metadata
+}
+
+func writeTestLockfile(t *testing.T, entry config.LockEntry) {
+	t.Helper()
+
+	lockfile, err := config.LoadLockfile(config.LockPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lockfile.Extensions["https://example.com/script.sh"] = entry
+	if err := lockfile.Save(); err != nil {
+		t.Fatal(err)
+	}
+}
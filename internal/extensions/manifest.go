@@ -0,0 +1,183 @@
+package extensions
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pomdtr/sunbeam/internal/types"
+)
+
+// ManifestFilename is the name of the manifest file at the root of multi-file extensions.
+// It must declare the entrypoint of the extension, relative to the root.
+// If it also declares the title and the commands of the extension, the entrypoint is not executed to extract the manifest.
+const ManifestFilename = "sunbeam.manifest.json"
+
+// HeaderMarker starts a manifest embedded in the header comment of a script.
+// The comment lines following the marker are parsed as JSON.
+const HeaderMarker = "@sunbeam"
+
+var commentPrefixes = []string{"//", "#", "--"}
+
+// DirEntrypoint reads the entrypoint of a multi-file extension from its manifest file.
+func DirEntrypoint(dir string) (string, error) {
+	manifestBytes, err := os.ReadFile(filepath.Join(dir, ManifestFilename))
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", ManifestFilename, err)
+	}
+
+	var manifest struct {
+		Entrypoint string `json:"entrypoint"`
+	}
+	if err := json.Unmarshal(manifestBytes, &manifest); err != nil {
+		return "", fmt.Errorf("failed to decode %s: %w", ManifestFilename, err)
+	}
+
+	if manifest.Entrypoint == "" {
+		return "", fmt.Errorf("%s does not declare an entrypoint", ManifestFilename)
+	}
+
+	entrypoint := filepath.Join(dir, manifest.Entrypoint)
+	if rel, err := filepath.Rel(dir, entrypoint); err != nil || strings.HasPrefix(rel, "..") {
+		return "", fmt.Errorf("entrypoint %s is outside of the extension directory", manifest.Entrypoint)
+	}
+
+	return entrypoint, nil
+}
+
+// staticManifest reads the manifest of an extension without executing it.
+// The manifest file of the extension directory takes precedence over the header of the entrypoint.
+func staticManifest(entrypoint string, dir string) (types.Manifest, bool, error) {
+	manifestPath := filepath.Join(dir, ManifestFilename)
+	if manifestBytes, err := os.ReadFile(manifestPath); err == nil {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(manifestBytes, &fields); err != nil {
+			return types.Manifest{}, false, fmt.Errorf("failed to decode %s: %w", ManifestFilename, err)
+		}
+
+		// the manifest file of a directory only applies to its own entrypoint
+		if dirEntrypoint, err := DirEntrypoint(dir); err == nil && dirEntrypoint == entrypoint {
+			if _, ok := fields["commands"]; ok {
+				manifest, err := decodeManifest(manifestBytes)
+				if err != nil {
+					return types.Manifest{}, false, fmt.Errorf("invalid %s: %w", ManifestFilename, err)
+				}
+
+				return manifest, true, nil
+			}
+		}
+	}
+
+	manifestBytes, err := headerManifest(entrypoint)
+	if err != nil {
+		return types.Manifest{}, false, err
+	}
+
+	if manifestBytes == nil {
+		return types.Manifest{}, false, nil
+	}
+
+	manifest, err := decodeManifest(manifestBytes)
+	if err != nil {
+		return types.Manifest{}, false, fmt.Errorf("invalid manifest header: %w", err)
+	}
+
+	return manifest, true, nil
+}
+
+// headerManifest extracts the manifest embedded in the header comment of a script, ex:
+//
+//	#!/bin/sh
+//	# @sunbeam
+//	# {
+//	#   "title": "Hello",
+//	#   "commands": [{"name": "hello", "title": "Say Hello", "mode": "detail"}]
+//	# }
+//
+// Only the leading comment block of the script is read.
+// It returns nil if the script has no manifest header, the script is then executed to extract its manifest.
+func headerManifest(entrypoint string) ([]byte, error) {
+	f, err := os.Open(entrypoint)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var prefix string
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if prefix == "" {
+			if line == "" || strings.HasPrefix(line, "#!") {
+				continue
+			}
+
+			var comment bool
+			for _, p := range commentPrefixes {
+				if !strings.HasPrefix(line, p) {
+					continue
+				}
+
+				comment = true
+				if strings.TrimSpace(strings.TrimPrefix(line, p)) == HeaderMarker {
+					prefix = p
+					break
+				}
+			}
+
+			// the header must be part of the leading comment block
+			if !comment {
+				return nil, nil
+			}
+
+			continue
+		}
+
+		if !strings.HasPrefix(line, prefix) {
+			break
+		}
+
+		lines = append(lines, strings.TrimPrefix(line, prefix))
+	}
+
+	if err := scanner.Err(); err != nil {
+		// the script is likely bundled or minified
+		if errors.Is(err, bufio.ErrTooLong) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("failed to read entrypoint: %w", err)
+	}
+
+	if prefix == "" {
+		return nil, nil
+	}
+
+	return []byte(strings.Join(lines, "\n")), nil
+}
+
+// sourceModTime returns the last modification time of the files the manifest is read from.
+func sourceModTime(entrypoint string, dir string) (time.Time, error) {
+	entrypointInfo, err := os.Stat(entrypoint)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	modTime := entrypointInfo.ModTime()
+	if dir == "" {
+		return modTime, nil
+	}
+
+	if manifestInfo, err := os.Stat(filepath.Join(dir, ManifestFilename)); err == nil && manifestInfo.ModTime().After(modTime) {
+		modTime = manifestInfo.ModTime()
+	}
+
+	return modTime, nil
+}
@@ -0,0 +1,260 @@
+package extensions
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/acarl005/stripansi"
+)
+
+// Methods of the JSON-RPC protocol spoken by persistent extensions.
+// Requests are written to the stdin of the extension, one per line,
+// and responses are read from its stdout, one per line.
+const (
+	MethodRun    = "run"
+	MethodReload = "reload"
+	MethodCancel = "cancel"
+)
+
+type rpcRequest struct {
+	Jsonrpc string `json:"jsonrpc"`
+	Id      int64  `json:"id,omitempty"`
+	Method  string `json:"method"`
+	Params  any    `json:"params,omitempty"`
+}
+
+type rpcResponse struct {
+	Id     int64           `json:"id"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Process is a long-running extension entrypoint, started with the --stdio flag.
+type Process struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stderr bytes.Buffer
+
+	mu      sync.Mutex
+	nextId  int64
+	pending map[int64]chan rpcResponse
+
+	done chan struct{}
+	err  error
+}
+
+var (
+	processMu sync.Mutex
+	processes = make(map[string]*Process)
+)
+
+// StartProcess starts a persistent extension, the command must run its entrypoint with the --stdio flag.
+func StartProcess(cmd *exec.Cmd) (*Process, error) {
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	process := &Process{
+		cmd:     cmd,
+		stdin:   stdin,
+		pending: make(map[int64]chan rpcResponse),
+		done:    make(chan struct{}),
+	}
+	cmd.Stderr = &process.stderr
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start extension: %w", err)
+	}
+
+	go process.read(stdout)
+	return process, nil
+}
+
+func (p *Process) read(stdout io.Reader) {
+	reader := bufio.NewReader(stdout)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			var res rpcResponse
+			if err := json.Unmarshal(line, &res); err == nil {
+				p.mu.Lock()
+				ch, ok := p.pending[res.Id]
+				delete(p.pending, res.Id)
+				p.mu.Unlock()
+
+				if ok {
+					ch <- res
+				}
+			}
+		}
+
+		if err != nil {
+			break
+		}
+	}
+
+	err := p.cmd.Wait()
+	p.mu.Lock()
+	if stderr := strings.TrimSpace(stripansi.Strip(p.stderr.String())); stderr != "" {
+		p.err = fmt.Errorf("extension exited: %s", stderr)
+	} else if err != nil {
+		p.err = fmt.Errorf("extension exited: %w", err)
+	} else {
+		p.err = fmt.Errorf("extension exited")
+	}
+	p.pending = nil
+	p.mu.Unlock()
+
+	close(p.done)
+}
+
+func (p *Process) write(req rpcRequest) error {
+	bts, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	if _, err := p.stdin.Write(append(bts, '\n')); err != nil {
+		return fmt.Errorf("failed to write request: %w", err)
+	}
+
+	return nil
+}
+
+// Call sends a request to the process and waits for its result.
+// If the context is done before the response arrives, a cancel notification is sent.
+func (p *Process) Call(ctx context.Context, method string, params any) ([]byte, error) {
+	p.mu.Lock()
+	if p.pending == nil {
+		p.mu.Unlock()
+		return nil, p.err
+	}
+
+	p.nextId++
+	id := p.nextId
+	ch := make(chan rpcResponse, 1)
+	p.pending[id] = ch
+
+	if err := p.write(rpcRequest{Jsonrpc: "2.0", Id: id, Method: method, Params: params}); err != nil {
+		delete(p.pending, id)
+		p.mu.Unlock()
+		return nil, err
+	}
+	p.mu.Unlock()
+
+	select {
+	case res := <-ch:
+		if res.Error != nil {
+			return nil, fmt.Errorf("command failed: %s", res.Error.Message)
+		}
+
+		return res.Result, nil
+	case <-ctx.Done():
+		p.mu.Lock()
+		if p.pending != nil {
+			delete(p.pending, id)
+			_ = p.write(rpcRequest{Jsonrpc: "2.0", Method: MethodCancel, Params: map[string]int64{"id": id}})
+		}
+		p.mu.Unlock()
+
+		return nil, ctx.Err()
+	case <-p.done:
+		return nil, p.err
+	}
+}
+
+// Stop closes the stdin of the process, and kills it if it does not exit in time.
+func (p *Process) Stop() {
+	_ = p.stdin.Close()
+
+	select {
+	case <-p.done:
+	case <-time.After(1 * time.Second):
+		_ = p.cmd.Process.Kill()
+		<-p.done
+	}
+}
+
+// processKey identifies the process of the extension.
+// Processes are started per alias, since each alias has its own preferences, storage and permissions.
+func (e Extension) processKey() string {
+	if e.Alias != "" {
+		return e.Alias
+	}
+
+	return e.Entrypoint
+}
+
+func (e Extension) process() (*Process, error) {
+	processMu.Lock()
+	defer processMu.Unlock()
+
+	if process, ok := processes[e.processKey()]; ok {
+		select {
+		case <-process.done:
+		default:
+			return process, nil
+		}
+	}
+
+	if err := e.createStorageDirs(); err != nil {
+		return nil, err
+	}
+
+	cmd, err := e.command(context.Background(), "--stdio")
+	if err != nil {
+		return nil, err
+	}
+	cmd.Dir = e.WorkDir()
+	cmd.Env = e.Environ()
+
+	process, err := StartProcess(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	processes[e.processKey()] = process
+	return process, nil
+}
+
+// StopProcess stops the persistent process of the extension, if it is running.
+// The next command starts it again, from the current version of the sources.
+func (e Extension) StopProcess() {
+	processMu.Lock()
+	defer processMu.Unlock()
+
+	if process, ok := processes[e.processKey()]; ok {
+		process.Stop()
+		delete(processes, e.processKey())
+	}
+}
+
+// StopProcesses stops every persistent extension started during the session.
+func StopProcesses() {
+	processMu.Lock()
+	defer processMu.Unlock()
+
+	for key, process := range processes {
+		process.Stop()
+		delete(processes, key)
+	}
+}
@@ -0,0 +1,51 @@
+package extensions
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// echoAlias answers every request with the alias the process was started with.
+const echoAlias = `#!/bin/sh
+while read -r line; do
+	id=$(echo "$line" | sed 's/.*"id":\([0-9]*\).*/\1/')
+	echo "{\"id\":$id,\"result\":\"$SUNBEAM_EXTENSION_ALIAS\"}"
+done
+`
+
+func TestProcessPerAlias(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	entrypoint := filepath.Join(t.TempDir(), "echo.sh")
+	if err := os.WriteFile(entrypoint, []byte(echoAlias), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(StopProcesses)
+
+	for _, alias := range []string{"first", "second", "first"} {
+		extension := Extension{Type: ExtensionTypeLocal, Entrypoint: entrypoint, Alias: alias}
+
+		process, err := extension.process()
+		if err != nil {
+			t.Fatalf("start %s: %v", alias, err)
+		}
+
+		result, err := process.Call(context.Background(), MethodRun, nil)
+		if err != nil {
+			t.Fatalf("call %s: %v", alias, err)
+		}
+
+		if got, want := string(result), `"`+alias+`"`; got != want {
+			t.Errorf("alias %s: result = %s, want %s", alias, got, want)
+		}
+	}
+
+	processMu.Lock()
+	defer processMu.Unlock()
+	if len(processes) != 2 {
+		t.Errorf("len(processes) = %d, want one per alias", len(processes))
+	}
+}
@@ -0,0 +1,299 @@
+package extensions
+
+import (
+	"bufio"
+	"context"
+	"debug/elf"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/pomdtr/sunbeam/internal/sandbox"
+	"github.com/pomdtr/sunbeam/internal/types"
+)
+
+// systemDirs are readable by every sandboxed extension, so that interpreters and their libraries can be loaded.
+var systemDirs = []string{
+	"/bin",
+	"/sbin",
+	"/usr",
+	"/lib",
+	"/lib32",
+	"/lib64",
+	"/etc",
+	"/opt",
+	"/nix/store",
+	"/run/current-system",
+	"/proc",
+}
+
+// PermissionsError is returned when an extension requests permissions that the user did not approve.
+type PermissionsError struct {
+	Requested types.Permissions
+}
+
+func (e *PermissionsError) Error() string {
+	return "the extension requests permissions that have not been approved, run sunbeam extension upgrade to review them"
+}
+
+// Sandboxed reports whether the extension processes are restricted to the permissions of the manifest.
+func (e Extension) Sandboxed() bool {
+	return e.Type != ExtensionTypeHttp && e.Manifest.Permissions != nil && sandbox.Supported()
+}
+
+// AllPermissions are requested by extensions which are no longer sandboxed.
+// Writing to the root grants access to the whole filesystem.
+var AllPermissions = types.Permissions{
+	Network:   true,
+	Clipboard: true,
+	Exec:      true,
+	Write:     []string{"/"},
+}
+
+// RequestedPermissions returns the permissions the user must approve to run the extension, or nil if it needs no approval.
+// An extension dropping the permissions block of its manifest once permissions were approved runs without a sandbox,
+// so it requests every permission.
+func (e Extension) RequestedPermissions() *types.Permissions {
+	if e.Type == ExtensionTypeHttp {
+		return nil
+	}
+
+	if e.Manifest.Permissions != nil {
+		return e.Manifest.Permissions
+	}
+
+	if e.Config.Permissions != nil {
+		all := AllPermissions
+		return &all
+	}
+
+	return nil
+}
+
+// CheckPermissions verifies that the permissions requested by the extension were approved in the config.
+func (e Extension) CheckPermissions() error {
+	requested := e.RequestedPermissions()
+	if requested == nil {
+		return nil
+	}
+
+	if e.Config.Permissions == nil || !e.Config.Permissions.Includes(*requested) {
+		return &PermissionsError{Requested: *requested}
+	}
+
+	return nil
+}
+
+// FormatPermissions describes permissions in a human readable way.
+func FormatPermissions(permissions types.Permissions) string {
+	lines := []string{
+		fmt.Sprintf("  - network access: %s", formatBool(permissions.Network)),
+		fmt.Sprintf("  - clipboard access: %s", formatBool(permissions.Clipboard)),
+		fmt.Sprintf("  - run other programs: %s", formatBool(permissions.Exec)),
+	}
+
+	for _, path := range permissions.Read {
+		lines = append(lines, fmt.Sprintf("  - read %s", path))
+	}
+
+	for _, path := range permissions.Write {
+		lines = append(lines, fmt.Sprintf("  - read and write %s", path))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func formatBool(b bool) string {
+	if b {
+		return "yes"
+	}
+
+	return "no"
+}
+
+func (e Extension) command(ctx context.Context, args ...string) (*exec.Cmd, error) {
+	if !e.Sandboxed() {
+		return exec.CommandContext(ctx, e.Entrypoint, args...), nil
+	}
+
+	policy, err := e.sandboxPolicy()
+	if err != nil {
+		return nil, err
+	}
+
+	return sandbox.CommandContext(ctx, policy, e.Entrypoint, args...)
+}
+
+// manifestCommand returns the command printing the manifest of an entrypoint.
+// The permissions of the extension are unknown until its manifest is read,
+// so the entrypoint runs in the most restrictive sandbox: without network access, and without write access.
+func manifestCommand(entrypoint string, dir string) (*exec.Cmd, error) {
+	if !sandbox.Supported() {
+		return exec.Command(entrypoint), nil
+	}
+
+	// single-file extensions do not own the directory they are stored in
+	source := entrypoint
+	if dirEntrypoint, err := DirEntrypoint(dir); err == nil && dirEntrypoint == entrypoint {
+		source = dir
+	}
+
+	interpreters, err := interpreters(entrypoint)
+	if err != nil {
+		return nil, err
+	}
+
+	// scripts may call other programs to print their manifest, they are run under the same restrictions
+	policy := sandbox.Policy{
+		Read:  append([]string{source}, systemDirs...),
+		Write: []string{"/dev/null"},
+		Exec:  append([]string{entrypoint}, interpreters...),
+	}
+	policy.Exec = append(policy.Exec, systemDirs...)
+	policy.Exec = append(policy.Exec, filepath.SplitList(os.Getenv("PATH"))...)
+
+	return sandbox.CommandContext(context.Background(), policy, entrypoint)
+}
+
+func (e Extension) sandboxPolicy() (sandbox.Policy, error) {
+	permissions := e.Manifest.Permissions
+
+	// single-file extensions do not own the directory they are stored in
+	source := e.Entrypoint
+	if e.Dir != "" {
+		source = e.Dir
+	}
+
+	policy := sandbox.Policy{
+		Network: permissions.Network,
+		Read:    append([]string{source}, systemDirs...),
+		Write:   append([]string{"/dev"}, e.storageDirs()...),
+		Exec:    []string{e.Entrypoint},
+	}
+
+	if permissions.Exec {
+		policy.Exec = append(policy.Exec, systemDirs...)
+		policy.Exec = append(policy.Exec, filepath.SplitList(os.Getenv("PATH"))...)
+	} else {
+		interpreters, err := interpreters(e.Entrypoint)
+		if err != nil {
+			return sandbox.Policy{}, err
+		}
+		policy.Exec = append(policy.Exec, interpreters...)
+	}
+
+	for _, path := range permissions.Read {
+		policy.Read = append(policy.Read, e.expandPath(path))
+	}
+
+	for _, path := range permissions.Write {
+		policy.Write = append(policy.Write, e.expandPath(path))
+	}
+
+	return policy, nil
+}
+
+// expandPath resolves a path of the manifest, relative to the extension directory.
+func (e Extension) expandPath(path string) string {
+	if path == "~" {
+		path = os.Getenv("HOME")
+	} else if strings.HasPrefix(path, "~/") {
+		path = filepath.Join(os.Getenv("HOME"), path[2:])
+	}
+
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(e.WorkDir(), path)
+	}
+
+	return filepath.Clean(path)
+}
+
+// interpreters returns the programs the kernel runs to execute the entrypoint:
+// the interpreter of its shebang, the program started by env, and the dynamic loader of each binary.
+func interpreters(entrypoint string) ([]string, error) {
+	var programs []string
+
+	shebang, err := readShebang(entrypoint)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(shebang) > 0 {
+		programs = append(programs, shebang[0])
+
+		if filepath.Base(shebang[0]) == "env" {
+			for _, arg := range shebang[1:] {
+				if strings.HasPrefix(arg, "-") || strings.Contains(arg, "=") {
+					continue
+				}
+
+				program, err := exec.LookPath(arg)
+				if err != nil {
+					return nil, fmt.Errorf("failed to find interpreter %s: %w", arg, err)
+				}
+				programs = append(programs, program)
+				break
+			}
+		}
+	} else {
+		programs = append(programs, entrypoint)
+	}
+
+	var paths []string
+	for _, program := range programs {
+		if resolved, err := filepath.EvalSymlinks(program); err == nil {
+			program = resolved
+		}
+		paths = append(paths, program)
+
+		if loader := elfInterpreter(program); loader != "" {
+			paths = append(paths, loader)
+		}
+	}
+
+	return paths, nil
+}
+
+func readShebang(entrypoint string) ([]string, error) {
+	f, err := os.Open(entrypoint)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	line, err := bufio.NewReader(f).ReadString('\n')
+	if err != nil && line == "" {
+		return nil, nil
+	}
+
+	if !strings.HasPrefix(line, "#!") {
+		return nil, nil
+	}
+
+	return strings.Fields(strings.TrimPrefix(line, "#!")), nil
+}
+
+func elfInterpreter(program string) string {
+	f, err := elf.Open(program)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	for _, prog := range f.Progs {
+		if prog.Type != elf.PT_INTERP {
+			continue
+		}
+
+		interp := make([]byte, prog.Filesz)
+		if _, err := prog.ReadAt(interp, 0); err != nil {
+			return ""
+		}
+
+		return strings.TrimRight(string(interp), "\x00")
+	}
+
+	return ""
+}
@@ -0,0 +1,55 @@
+package extensions
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/pomdtr/sunbeam/internal/config"
+	"github.com/pomdtr/sunbeam/internal/types"
+)
+
+func TestCheckPermissions(t *testing.T) {
+	network := &types.Permissions{Network: true}
+	readHome := &types.Permissions{Read: []string{"~"}}
+	all := AllPermissions
+
+	for _, tc := range []struct {
+		name      string
+		requested *types.Permissions
+		approved  *types.Permissions
+		err       bool
+	}{
+		{name: "unsandboxed extension", requested: nil, approved: nil},
+		{name: "approved permissions", requested: network, approved: network},
+		{name: "unapproved permissions", requested: network, approved: nil, err: true},
+		{name: "extended permissions", requested: &types.Permissions{Network: true, Exec: true}, approved: network, err: true},
+		{name: "narrowed permissions", requested: readHome, approved: &types.Permissions{Write: []string{"~"}}},
+		{name: "dropped permissions", requested: nil, approved: network, err: true},
+		{name: "dropped permissions approved", requested: nil, approved: &all},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			extension := Extension{
+				Type:     ExtensionTypeLocal,
+				Manifest: types.Manifest{Permissions: tc.requested},
+				Config:   config.ExtensionConfig{Permissions: tc.approved},
+			}
+
+			err := extension.CheckPermissions()
+			if !tc.err {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			var permissionsErr *PermissionsError
+			if !errors.As(err, &permissionsErr) {
+				t.Fatalf("error = %v, want a permissions error", err)
+			}
+
+			if tc.requested == nil && !permissionsErr.Requested.Includes(AllPermissions) {
+				t.Errorf("requested = %+v, want every permission", permissionsErr.Requested)
+			}
+		})
+	}
+}
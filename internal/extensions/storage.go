@@ -0,0 +1,154 @@
+package extensions
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pomdtr/sunbeam/internal/utils"
+)
+
+// Version is the version of sunbeam, passed to extensions in the SUNBEAM_VERSION environment variable.
+var Version = "dev"
+
+// ValidateAlias checks that an alias can be used as the name of the storage directories of an extension.
+func ValidateAlias(alias string) error {
+	if alias == "" || alias == "." || strings.Contains(alias, "..") || strings.ContainsAny(alias, `/\`) {
+		return fmt.Errorf("invalid alias: %q", alias)
+	}
+
+	return nil
+}
+
+// DataDir returns the directory where an extension stores persistent data.
+func DataDir(alias string) string {
+	return filepath.Join(utils.ConfigDir(), "storage", alias)
+}
+
+// CacheDir returns the directory where an extension stores data that can be recomputed.
+func CacheDir(alias string) string {
+	return filepath.Join(utils.CacheDir(), "storage", alias)
+}
+
+// storageDirs returns the data and cache directories of the extension, or nil if it is not installed.
+func (e Extension) storageDirs() []string {
+	if e.Alias == "" {
+		return nil
+	}
+
+	return []string{e.dataDir(), e.cacheDir()}
+}
+
+func (e Extension) dataDir() string {
+	if e.StorageDir != "" {
+		return filepath.Join(e.StorageDir, "data")
+	}
+
+	return DataDir(e.Alias)
+}
+
+func (e Extension) cacheDir() string {
+	if e.StorageDir != "" {
+		return filepath.Join(e.StorageDir, "cache")
+	}
+
+	return CacheDir(e.Alias)
+}
+
+func (e Extension) createStorageDirs() error {
+	for _, dir := range e.storageDirs() {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create directory: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// RenameStorage moves the data and cache directories of an extension to its new alias.
+func RenameStorage(alias string, newAlias string) error {
+	if err := ValidateAlias(alias); err != nil {
+		return err
+	}
+
+	if err := ValidateAlias(newAlias); err != nil {
+		return err
+	}
+
+	for _, dirs := range [][2]string{
+		{DataDir(alias), DataDir(newAlias)},
+		{CacheDir(alias), CacheDir(newAlias)},
+	} {
+		if _, err := os.Stat(dirs[0]); os.IsNotExist(err) {
+			continue
+		}
+
+		if err := os.RemoveAll(dirs[1]); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", dirs[1], err)
+		}
+
+		if err := os.Rename(dirs[0], dirs[1]); err != nil {
+			return fmt.Errorf("failed to move %s: %w", dirs[0], err)
+		}
+	}
+
+	return nil
+}
+
+// RemoveStorage deletes the data and cache directories of an extension.
+func RemoveStorage(alias string) error {
+	if err := ValidateAlias(alias); err != nil {
+		return err
+	}
+
+	for _, dir := range []string{DataDir(alias), CacheDir(alias)} {
+		if err := os.RemoveAll(dir); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", dir, err)
+		}
+	}
+
+	return nil
+}
+
+// storageEnv returns the environment variables describing the extension to its processes.
+func (e Extension) storageEnv() []string {
+	env := []string{fmt.Sprintf("SUNBEAM_VERSION=%s", Version)}
+	if e.Alias == "" {
+		return env
+	}
+
+	return append(env,
+		fmt.Sprintf("SUNBEAM_EXTENSION_ALIAS=%s", e.Alias),
+		fmt.Sprintf("SUNBEAM_EXTENSION_DATA_DIR=%s", e.dataDir()),
+		fmt.Sprintf("SUNBEAM_EXTENSION_CACHE_DIR=%s", e.cacheDir()),
+	)
+}
+
+// DirSize returns the total size of the files under a directory, or 0 if it does not exist.
+func DirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+
+		return nil
+	})
+
+	return size, err
+}
@@ -0,0 +1,225 @@
+package extensions
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/pomdtr/sunbeam/internal/config"
+	"github.com/pomdtr/sunbeam/internal/types"
+	"github.com/pomdtr/sunbeam/internal/utils"
+)
+
+// Changes describes what changed between two versions of a manifest.
+type Changes struct {
+	OldVersion       string
+	NewVersion       string
+	AddedCommands    []string
+	RemovedCommands  []string
+	RequiredParams   []string
+	AddedPreferences []types.Input
+}
+
+// Empty reports whether the manifest changes are worth reporting.
+func (c Changes) Empty() bool {
+	return c.OldVersion == c.NewVersion && len(c.AddedCommands) == 0 && len(c.RemovedCommands) == 0 && len(c.RequiredParams) == 0 && len(c.AddedPreferences) == 0
+}
+
+// DiffManifests compares two versions of a manifest.
+// Required params are reported for commands present in both versions, as command.param.
+func DiffManifests(oldManifest types.Manifest, newManifest types.Manifest) Changes {
+	changes := Changes{
+		OldVersion: oldManifest.Version,
+		NewVersion: newManifest.Version,
+	}
+
+	oldCommands := make(map[string]types.CommandSpec)
+	for _, command := range oldManifest.Commands {
+		oldCommands[command.Name] = command
+	}
+
+	newCommands := make(map[string]bool)
+	for _, command := range newManifest.Commands {
+		newCommands[command.Name] = true
+
+		oldCommand, ok := oldCommands[command.Name]
+		if !ok {
+			changes.AddedCommands = append(changes.AddedCommands, command.Name)
+			continue
+		}
+
+		oldRequired := make(map[string]bool)
+		for _, param := range oldCommand.Params {
+			oldRequired[param.Name] = param.Required
+		}
+
+		for _, param := range command.Params {
+			if param.Required && !oldRequired[param.Name] {
+				changes.RequiredParams = append(changes.RequiredParams, fmt.Sprintf("%s.%s", command.Name, param.Name))
+			}
+		}
+	}
+
+	for _, command := range oldManifest.Commands {
+		if !newCommands[command.Name] {
+			changes.RemovedCommands = append(changes.RemovedCommands, command.Name)
+		}
+	}
+
+	oldPreferences := make(map[string]bool)
+	for _, preference := range oldManifest.Preferences {
+		oldPreferences[preference.Name] = true
+	}
+
+	for _, preference := range newManifest.Preferences {
+		if !oldPreferences[preference.Name] {
+			changes.AddedPreferences = append(changes.AddedPreferences, preference)
+		}
+	}
+
+	return changes
+}
+
+// Upgrade fetches the latest version of an extension, and reports the changes to its manifest.
+func Upgrade(extensionConfig config.ExtensionConfig) (Changes, error) {
+	extensionDir, err := extensionDir(extensionConfig.Origin)
+	if err != nil {
+		return Changes{}, err
+	}
+
+	manifestPath := filepath.Join(extensionDir, "manifest.json")
+	oldManifest := cachedManifest(manifestPath)
+
+	if IsRemote(extensionConfig.Origin) {
+		metadata, err := Fetch(extensionConfig.Origin, extensionDir)
+		if err != nil {
+			return Changes{}, err
+		}
+
+		if metadata.Type == ExtensionTypeHttp {
+			newManifest, err := readManifest(manifestPath)
+			if err != nil {
+				return Changes{}, err
+			}
+
+			return DiffManifests(oldManifest, newManifest), nil
+		}
+
+		if err := pinChecksum(extensionConfig.Origin, metadata, extensionDir); err != nil {
+			return Changes{}, err
+		}
+
+		newManifest, err := cacheManifest(metadata.Entrypoint, metadata.workDir(), manifestPath)
+		if err != nil {
+			return Changes{}, err
+		}
+
+		return DiffManifests(oldManifest, newManifest), nil
+	}
+
+	extension, err := LoadExtension(extensionConfig.Origin)
+	if err != nil {
+		return Changes{}, err
+	}
+
+	newManifest, err := cacheManifest(extension.Entrypoint, extension.WorkDir(), manifestPath)
+	if err != nil {
+		return Changes{}, err
+	}
+
+	return DiffManifests(oldManifest, newManifest), nil
+}
+
+// PreviewUpgrade reports the changes an upgrade would make, without replacing the installed extension.
+// Remote extensions are fetched to a temporary directory.
+func PreviewUpgrade(extensionConfig config.ExtensionConfig) (Changes, error) {
+	extensionDir, err := extensionDir(extensionConfig.Origin)
+	if err != nil {
+		return Changes{}, err
+	}
+
+	oldManifest := cachedManifest(filepath.Join(extensionDir, "manifest.json"))
+
+	if IsRemote(extensionConfig.Origin) {
+		stagingDir, err := os.MkdirTemp("", "sunbeam-upgrade-*")
+		if err != nil {
+			return Changes{}, err
+		}
+		defer os.RemoveAll(stagingDir)
+
+		metadata, err := Fetch(extensionConfig.Origin, stagingDir)
+		if err != nil {
+			return Changes{}, err
+		}
+
+		if metadata.Type == ExtensionTypeHttp {
+			newManifest, err := readManifest(filepath.Join(stagingDir, "manifest.json"))
+			if err != nil {
+				return Changes{}, err
+			}
+
+			return DiffManifests(oldManifest, newManifest), nil
+		}
+
+		newManifest, err := previewManifest(metadata.Entrypoint, metadata.workDir())
+		if err != nil {
+			return Changes{}, err
+		}
+
+		return DiffManifests(oldManifest, newManifest), nil
+	}
+
+	entrypoint, err := LoadEntrypoint(extensionConfig.Origin)
+	if err != nil {
+		return Changes{}, err
+	}
+
+	dir := filepath.Dir(entrypoint)
+	if info, err := os.Stat(entrypoint); err == nil && info.IsDir() {
+		dir = entrypoint
+		if entrypoint, err = DirEntrypoint(dir); err != nil {
+			return Changes{}, err
+		}
+	}
+
+	newManifest, err := previewManifest(entrypoint, dir)
+	if err != nil {
+		return Changes{}, err
+	}
+
+	return DiffManifests(oldManifest, newManifest), nil
+}
+
+// previewManifest reads the static manifest of an extension, previews never execute the entrypoint.
+func previewManifest(entrypoint string, dir string) (types.Manifest, error) {
+	manifest, ok, err := staticManifest(entrypoint, dir)
+	if err != nil {
+		return types.Manifest{}, err
+	}
+
+	if !ok {
+		return types.Manifest{}, fmt.Errorf("the extension does not declare a static manifest, its changes cannot be previewed without running it")
+	}
+
+	return manifest, nil
+}
+
+func extensionDir(origin string) (string, error) {
+	hash, err := Hash(origin)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(utils.CacheDir(), "extensions", hash), nil
+}
+
+// cachedManifest returns the manifest of the installed version of an extension,
+// or an empty manifest if it was never loaded.
+func cachedManifest(manifestPath string) types.Manifest {
+	manifest, err := readManifest(manifestPath)
+	if err != nil {
+		return types.Manifest{}
+	}
+
+	return manifest
+}
@@ -0,0 +1,47 @@
+package extensions
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pomdtr/sunbeam/internal/config"
+)
+
+func TestPreviewUpgradeDoesNotExecute(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	dir := t.TempDir()
+	marker := filepath.Join(dir, "executed")
+	entrypoint := filepath.Join(dir, "script.sh")
+	script := "#!/bin/sh\ntouch " + marker + "\necho '{\"title\": \"Test\", \"commands\": []}'\n"
+	if err := os.WriteFile(entrypoint, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := PreviewUpgrade(config.ExtensionConfig{Origin: entrypoint}); err == nil {
+		t.Error("expected an error for an extension without a static manifest")
+	}
+
+	if _, err := os.Stat(marker); !os.IsNotExist(err) {
+		t.Error("the entrypoint was executed")
+	}
+
+	static := "#!/bin/sh\n# @sunbeam\n# {\"title\": \"Test\", \"version\": \"1.0.0\", \"commands\": []}\ntouch " + marker + "\n"
+	if err := os.WriteFile(entrypoint, []byte(static), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := PreviewUpgrade(config.ExtensionConfig{Origin: entrypoint})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if changes.NewVersion != "1.0.0" {
+		t.Errorf("new version = %q, want the version of the static manifest", changes.NewVersion)
+	}
+
+	if _, err := os.Stat(marker); !os.IsNotExist(err) {
+		t.Error("the entrypoint was executed")
+	}
+}
@@ -0,0 +1,18 @@
+// Package sandbox restricts the filesystem and network access of extension processes.
+//
+// On Linux, the network is cut by running the process in new user and network namespaces,
+// and the filesystem is restricted with Landlock. Landlock rules are applied by a helper
+// command of the sunbeam binary, which execs the sandboxed program once restricted.
+package sandbox
+
+// HelperCommand is the hidden sunbeam subcommand applying the filesystem rules.
+const HelperCommand = "sandbox"
+
+// Policy lists what a sandboxed process is allowed to do.
+// Paths must be absolute, and grant access to the whole hierarchy beneath them.
+type Policy struct {
+	Network bool     `json:"network,omitempty"`
+	Read    []string `json:"read,omitempty"`
+	Write   []string `json:"write,omitempty"`
+	Exec    []string `json:"exec,omitempty"`
+}
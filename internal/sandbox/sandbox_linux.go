@@ -0,0 +1,185 @@
+//go:build linux
+
+package sandbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	accessRead    = unix.LANDLOCK_ACCESS_FS_READ_FILE | unix.LANDLOCK_ACCESS_FS_READ_DIR
+	accessExecute = unix.LANDLOCK_ACCESS_FS_EXECUTE
+	accessWrite   = unix.LANDLOCK_ACCESS_FS_WRITE_FILE |
+		unix.LANDLOCK_ACCESS_FS_REMOVE_DIR |
+		unix.LANDLOCK_ACCESS_FS_REMOVE_FILE |
+		unix.LANDLOCK_ACCESS_FS_MAKE_CHAR |
+		unix.LANDLOCK_ACCESS_FS_MAKE_DIR |
+		unix.LANDLOCK_ACCESS_FS_MAKE_REG |
+		unix.LANDLOCK_ACCESS_FS_MAKE_SOCK |
+		unix.LANDLOCK_ACCESS_FS_MAKE_FIFO |
+		unix.LANDLOCK_ACCESS_FS_MAKE_BLOCK |
+		unix.LANDLOCK_ACCESS_FS_MAKE_SYM |
+		unix.LANDLOCK_ACCESS_FS_REFER |
+		unix.LANDLOCK_ACCESS_FS_TRUNCATE
+
+	// rights that can be granted on a regular file
+	accessFile = unix.LANDLOCK_ACCESS_FS_EXECUTE |
+		unix.LANDLOCK_ACCESS_FS_WRITE_FILE |
+		unix.LANDLOCK_ACCESS_FS_READ_FILE |
+		unix.LANDLOCK_ACCESS_FS_TRUNCATE
+)
+
+// Supported reports whether the kernel supports Landlock.
+func Supported() bool {
+	_, err := landlockABI()
+	return err == nil
+}
+
+func landlockABI() (int, error) {
+	abi, _, errno := unix.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET, 0, 0, unix.LANDLOCK_CREATE_RULESET_VERSION)
+	if errno != 0 {
+		return 0, errno
+	}
+
+	return int(abi), nil
+}
+
+// handledAccess returns the filesystem rights known by a version of the Landlock ABI.
+func handledAccess(abi int) uint64 {
+	access := uint64(accessRead | accessExecute | accessWrite)
+	if abi < 2 {
+		access &^= unix.LANDLOCK_ACCESS_FS_REFER
+	}
+
+	if abi < 3 {
+		access &^= unix.LANDLOCK_ACCESS_FS_TRUNCATE
+	}
+
+	return access
+}
+
+// CommandContext returns a command running the program through the sandbox helper.
+func CommandContext(ctx context.Context, policy Policy, name string, args ...string) (*exec.Cmd, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate sunbeam executable: %w", err)
+	}
+
+	policyBytes, err := json.Marshal(policy)
+	if err != nil {
+		return nil, err
+	}
+
+	helperArgs := []string{HelperCommand, "--policy", string(policyBytes), "--", name}
+	cmd := exec.CommandContext(ctx, executable, append(helperArgs, args...)...)
+	if !policy.Network {
+		cmd.SysProcAttr = &syscall.SysProcAttr{
+			Cloneflags: syscall.CLONE_NEWUSER | syscall.CLONE_NEWNET,
+			UidMappings: []syscall.SysProcIDMap{
+				{ContainerID: os.Getuid(), HostID: os.Getuid(), Size: 1},
+			},
+			GidMappings: []syscall.SysProcIDMap{
+				{ContainerID: os.Getgid(), HostID: os.Getgid(), Size: 1},
+			},
+		}
+	}
+
+	return cmd, nil
+}
+
+// Exec restricts the filesystem access of the current process, then replaces it with the program.
+func Exec(policy Policy, argv []string) error {
+	if len(argv) == 0 {
+		return errors.New("no program to run")
+	}
+
+	program, err := exec.LookPath(argv[0])
+	if err != nil {
+		return err
+	}
+
+	abi, err := landlockABI()
+	if err != nil {
+		return fmt.Errorf("landlock is not supported by the kernel: %w", err)
+	}
+	handled := handledAccess(abi)
+
+	// landlock and no_new_privs apply to the calling thread, which must be the one calling execve
+	runtime.LockOSThread()
+
+	attr := unix.LandlockRulesetAttr{Access_fs: handled}
+	rulesetFd, _, errno := unix.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET, uintptr(unsafe.Pointer(&attr)), unsafe.Sizeof(attr), 0)
+	if errno != 0 {
+		return fmt.Errorf("failed to create landlock ruleset: %w", errno)
+	}
+	defer unix.Close(int(rulesetFd))
+
+	rules := make(map[string]uint64)
+	for _, path := range policy.Read {
+		rules[path] |= accessRead
+	}
+
+	for _, path := range policy.Write {
+		rules[path] |= accessRead | accessWrite
+	}
+
+	for _, path := range policy.Exec {
+		rules[path] |= accessRead | accessExecute
+	}
+
+	for path, access := range rules {
+		if err := addRule(int(rulesetFd), path, access&handled); err != nil {
+			return err
+		}
+	}
+
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		return fmt.Errorf("failed to set no_new_privs: %w", err)
+	}
+
+	if _, _, errno := unix.Syscall(unix.SYS_LANDLOCK_RESTRICT_SELF, rulesetFd, 0, 0); errno != 0 {
+		return fmt.Errorf("failed to enforce landlock ruleset: %w", errno)
+	}
+
+	return syscall.Exec(program, argv, os.Environ())
+}
+
+func addRule(rulesetFd int, path string, access uint64) error {
+	fd, err := unix.Open(path, unix.O_PATH|unix.O_CLOEXEC, 0)
+	if errors.Is(err, unix.ENOENT) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer unix.Close(fd)
+
+	var stat unix.Stat_t
+	if err := unix.Fstat(fd, &stat); err != nil {
+		return fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+
+	if stat.Mode&unix.S_IFMT != unix.S_IFDIR {
+		access &= accessFile
+	}
+
+	rule := unix.LandlockPathBeneathAttr{
+		Allowed_access: access,
+		Parent_fd:      int32(fd),
+	}
+
+	if _, _, errno := unix.Syscall6(unix.SYS_LANDLOCK_ADD_RULE, uintptr(rulesetFd), unix.LANDLOCK_RULE_PATH_BENEATH, uintptr(unsafe.Pointer(&rule)), 0, 0, 0); errno != 0 {
+		return fmt.Errorf("failed to add landlock rule for %s: %w", path, errno)
+	}
+
+	return nil
+}
@@ -0,0 +1,23 @@
+//go:build !linux
+
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+)
+
+// Supported reports whether the platform supports sandboxing.
+func Supported() bool {
+	return false
+}
+
+// CommandContext returns a command running the program without restrictions.
+func CommandContext(ctx context.Context, policy Policy, name string, args ...string) (*exec.Cmd, error) {
+	return exec.CommandContext(ctx, name, args...), nil
+}
+
+func Exec(policy Policy, argv []string) error {
+	return errors.New("sandboxing is only supported on linux")
+}
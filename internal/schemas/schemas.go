@@ -23,6 +23,8 @@ var schemaUrls = []string{
 	"config.schema.json",
 }
 
+const listItemSchema = "list.schema.json#/definitions/item"
+
 func init() {
 	compiler := jsonschema.NewCompiler()
 	compiler.Draft = jsonschema.Draft7
@@ -47,6 +49,12 @@ func init() {
 
 		schemas[url] = schema
 	}
+
+	item, err := compiler.Compile(listItemSchema)
+	if err != nil {
+		panic(err)
+	}
+	schemas[listItemSchema] = item
 }
 
 func formatValidationError(ve *jsonschema.ValidationError) string {
@@ -81,6 +89,10 @@ func ValidateList(input []byte) error {
 	return validateSchema("list.schema.json", input)
 }
 
+func ValidateListItem(input []byte) error {
+	return validateSchema(listItemSchema, input)
+}
+
 func ValidateManifest(input []byte) error {
 	return validateSchema("manifest.schema.json", input)
 }
@@ -0,0 +1,30 @@
+package schemas
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestValidateManifestStream(t *testing.T) {
+	for _, tc := range []struct {
+		mode   string
+		stream bool
+		valid  bool
+	}{
+		{mode: "filter", stream: true, valid: true},
+		{mode: "search", stream: true, valid: true},
+		{mode: "detail", stream: true, valid: false},
+		{mode: "tty", stream: true, valid: false},
+		{mode: "silent", stream: true, valid: false},
+		{mode: "detail", stream: false, valid: true},
+	} {
+		manifest := fmt.Sprintf(`{"title": "Test", "commands": [{"name": "test", "title": "Test", "mode": %q, "stream": %t}]}`, tc.mode, tc.stream)
+
+		err := ValidateManifest([]byte(manifest))
+		if tc.valid && err != nil {
+			t.Errorf("%s (stream: %t): unexpected error: %v", tc.mode, tc.stream, err)
+		} else if !tc.valid && err == nil {
+			t.Errorf("%s (stream: %t): expected an error", tc.mode, tc.stream)
+		}
+	}
+}
@@ -0,0 +1,374 @@
+// Package secrets stores the values of password preferences encrypted, outside of the config file.
+//
+// Values are encrypted with AES-GCM. The key is derived from the SUNBEAM_SECRETS_PASSPHRASE
+// environment variable if it is set, or read from a key file generated on first use.
+// The config only keeps a reference to the secret, ex: {"secret": "github.token"}.
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/pomdtr/sunbeam/internal/types"
+	"github.com/pomdtr/sunbeam/internal/utils"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const PassphraseEnv = "SUNBEAM_SECRETS_PASSPHRASE"
+
+const pbkdf2Iterations = 600_000
+
+// derived keys are cached by passphrase and salt, since the payload of every command run is revealed
+var (
+	derivedKeysMu sync.Mutex
+	derivedKeys   = make(map[[2]string][]byte)
+)
+
+type Store struct {
+	Salt    string            `json:"salt"`
+	Secrets map[string]string `json:"secrets"`
+	path    string            `json:"-"`
+	key     []byte            `json:"-"`
+}
+
+func Path() string {
+	return filepath.Join(utils.ConfigDir(), "secrets.json")
+}
+
+func KeyPath() string {
+	return filepath.Join(utils.ConfigDir(), "secrets.key")
+}
+
+func Load(storePath string) (*Store, error) {
+	store := Store{
+		Secrets: make(map[string]string),
+		path:    storePath,
+	}
+
+	storeBytes, err := os.ReadFile(storePath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to load secrets: %w", err)
+	} else if err == nil {
+		if err := json.Unmarshal(storeBytes, &store); err != nil {
+			return nil, fmt.Errorf("failed to decode secrets: %w", err)
+		}
+
+		if store.Secrets == nil {
+			store.Secrets = make(map[string]string)
+		}
+	}
+
+	if store.Salt == "" {
+		salt := make([]byte, 16)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, err
+		}
+		store.Salt = base64.StdEncoding.EncodeToString(salt)
+	}
+
+	return &store, nil
+}
+
+func (s *Store) Save() error {
+	storeBytes, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode secrets: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	if err := os.WriteFile(s.path, append(storeBytes, '\n'), 0600); err != nil {
+		return fmt.Errorf("failed to write secrets: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Store) Get(name string) (string, error) {
+	encoded, ok := s.Secrets[name]
+	if !ok {
+		return "", fmt.Errorf("secret %s not found", name)
+	}
+
+	ciphertext, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("invalid secret %s: %w", name, err)
+	}
+
+	gcm, err := s.cipher()
+	if err != nil {
+		return "", err
+	}
+
+	if len(ciphertext) < gcm.NonceSize() {
+		return "", fmt.Errorf("invalid secret %s", name)
+	}
+
+	nonce, ciphertext := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, []byte(name))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt secret %s, check your key or passphrase", name)
+	}
+
+	return string(plaintext), nil
+}
+
+func (s *Store) Set(name string, value string) error {
+	gcm, err := s.cipher()
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	// the name is authenticated, so that a secret cannot be swapped with another one
+	ciphertext := gcm.Seal(nonce, nonce, []byte(value), []byte(name))
+	s.Secrets[name] = base64.StdEncoding.EncodeToString(ciphertext)
+
+	return nil
+}
+
+func (s *Store) Delete(name string) {
+	delete(s.Secrets, name)
+}
+
+func (s *Store) cipher() (cipher.AEAD, error) {
+	if s.key == nil {
+		key, err := s.loadKey()
+		if err != nil {
+			return nil, err
+		}
+		s.key = key
+	}
+
+	block, err := aes.NewCipher(s.key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+func (s *Store) loadKey() ([]byte, error) {
+	if passphrase, ok := os.LookupEnv(PassphraseEnv); ok {
+		derivedKeysMu.Lock()
+		defer derivedKeysMu.Unlock()
+
+		cacheKey := [2]string{passphrase, s.Salt}
+		if key, ok := derivedKeys[cacheKey]; ok {
+			return key, nil
+		}
+
+		salt, err := base64.StdEncoding.DecodeString(s.Salt)
+		if err != nil {
+			return nil, fmt.Errorf("invalid salt: %w", err)
+		}
+
+		key := pbkdf2.Key([]byte(passphrase), salt, pbkdf2Iterations, 32, sha256.New)
+		derivedKeys[cacheKey] = key
+		return key, nil
+	}
+
+	key, err := os.ReadFile(KeyPath())
+	if err == nil {
+		if len(key) != 32 {
+			return nil, fmt.Errorf("invalid key file: %s", KeyPath())
+		}
+
+		return key, nil
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+
+	key = make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(KeyPath()), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	if err := os.WriteFile(KeyPath(), key, 0600); err != nil {
+		return nil, fmt.Errorf("failed to write key file: %w", err)
+	}
+
+	return key, nil
+}
+
+// Name returns the name of the secret holding a preference of an extension.
+func Name(alias string, preference string) string {
+	return fmt.Sprintf("%s.%s", alias, preference)
+}
+
+// Reference returns the value stored in the config in place of a secret.
+func Reference(name string) map[string]any {
+	return map[string]any{"secret": name}
+}
+
+// ParseReference returns the name of the secret referenced by a config value.
+func ParseReference(value any) (string, bool) {
+	ref, ok := value.(map[string]any)
+	if !ok || len(ref) != 1 {
+		return "", false
+	}
+
+	name, ok := ref["secret"].(string)
+	return name, ok
+}
+
+// Protect moves the values of password preferences to the store, and returns the preferences to write in the config.
+func Protect(alias string, inputs []types.Input, preferences map[string]any) (map[string]any, error) {
+	var store *Store
+	protected := make(map[string]any)
+	for name, value := range preferences {
+		protected[name] = value
+	}
+
+	for _, input := range inputs {
+		if input.Type != types.InputPassword {
+			continue
+		}
+
+		value, ok := preferences[input.Name].(string)
+		if !ok || value == "" {
+			continue
+		}
+
+		if store == nil {
+			s, err := Load(Path())
+			if err != nil {
+				return nil, err
+			}
+			store = s
+		}
+
+		name := Name(alias, input.Name)
+		if err := store.Set(name, value); err != nil {
+			return nil, err
+		}
+		protected[input.Name] = Reference(name)
+	}
+
+	if store == nil {
+		return protected, nil
+	}
+
+	if err := store.Save(); err != nil {
+		return nil, err
+	}
+
+	return protected, nil
+}
+
+// Reveal returns a copy of the preferences, where references are replaced by the values of the secrets.
+func Reveal(preferences map[string]any) (map[string]any, error) {
+	var store *Store
+	revealed := make(map[string]any)
+	for key, value := range preferences {
+		name, ok := ParseReference(value)
+		if !ok {
+			revealed[key] = value
+			continue
+		}
+
+		if store == nil {
+			s, err := Load(Path())
+			if err != nil {
+				return nil, err
+			}
+			store = s
+		}
+
+		secret, err := store.Get(name)
+		if err != nil {
+			return nil, err
+		}
+		revealed[key] = secret
+	}
+
+	return revealed, nil
+}
+
+// Rename moves the secrets of an extension to its new alias, and returns the preferences referencing them.
+func Rename(alias string, newAlias string, preferences map[string]any) (map[string]any, error) {
+	var store *Store
+	renamed := make(map[string]any)
+	for key, value := range preferences {
+		renamed[key] = value
+
+		name, ok := ParseReference(value)
+		if !ok {
+			continue
+		}
+
+		if store == nil {
+			s, err := Load(Path())
+			if err != nil {
+				return nil, err
+			}
+			store = s
+		}
+
+		secret, err := store.Get(name)
+		if err != nil {
+			return nil, err
+		}
+
+		// the name is authenticated, the secret is encrypted again under its new name
+		newName := Name(newAlias, key)
+		if err := store.Set(newName, secret); err != nil {
+			return nil, err
+		}
+		store.Delete(name)
+		renamed[key] = Reference(newName)
+	}
+
+	if store == nil {
+		return renamed, nil
+	}
+
+	if err := store.Save(); err != nil {
+		return nil, err
+	}
+
+	return renamed, nil
+}
+
+// Remove deletes the secrets of an extension from the store.
+func Remove(preferences map[string]any) error {
+	var names []string
+	for _, value := range preferences {
+		if name, ok := ParseReference(value); ok {
+			names = append(names, name)
+		}
+	}
+
+	if len(names) == 0 {
+		return nil
+	}
+
+	store, err := Load(Path())
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		store.Delete(name)
+	}
+
+	return store.Save()
+}
@@ -0,0 +1,131 @@
+package secrets
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/pomdtr/sunbeam/internal/types"
+)
+
+var passwordInputs = []types.Input{
+	{Name: "token", Type: types.InputPassword},
+	{Name: "user", Type: types.InputText},
+}
+
+func TestProtectReveal(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		// setup configures the key, before the secrets are protected
+		setup func(t *testing.T)
+		// rotate changes the key, before the secrets are revealed
+		rotate func(t *testing.T)
+	}{
+		{
+			name:  "key file",
+			setup: func(t *testing.T) {},
+		},
+		{
+			name:  "wrong key file",
+			setup: func(t *testing.T) {},
+			rotate: func(t *testing.T) {
+				if err := os.WriteFile(KeyPath(), bytes.Repeat([]byte{1}, 32), 0600); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+		{
+			name: "passphrase",
+			setup: func(t *testing.T) {
+				t.Setenv(PassphraseEnv, "correct horse battery staple")
+			},
+		},
+		{
+			name: "wrong passphrase",
+			setup: func(t *testing.T) {
+				t.Setenv(PassphraseEnv, "correct horse battery staple")
+			},
+			rotate: func(t *testing.T) {
+				t.Setenv(PassphraseEnv, "incorrect horse battery staple")
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+			tc.setup(t)
+
+			protected, err := Protect("github", passwordInputs, map[string]any{"token": "hunter2", "user": "pomdtr"})
+			if err != nil {
+				t.Fatalf("protect: %v", err)
+			}
+
+			if name, ok := ParseReference(protected["token"]); !ok || name != "github.token" {
+				t.Errorf("token = %v, want a reference to github.token", protected["token"])
+			}
+
+			if protected["user"] != "pomdtr" {
+				t.Errorf("user = %v, text preferences must be kept as is", protected["user"])
+			}
+
+			storeBytes, err := os.ReadFile(Path())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if strings.Contains(string(storeBytes), "hunter2") {
+				t.Error("the secret is stored in clear text")
+			}
+
+			if tc.rotate != nil {
+				tc.rotate(t)
+				if _, err := Reveal(protected); err == nil {
+					t.Error("expected an error with the wrong key")
+				}
+				return
+			}
+
+			revealed, err := Reveal(protected)
+			if err != nil {
+				t.Fatalf("reveal: %v", err)
+			}
+
+			if revealed["token"] != "hunter2" || revealed["user"] != "pomdtr" {
+				t.Errorf("revealed = %v, want the original preferences", revealed)
+			}
+		})
+	}
+}
+
+func TestSecretsAreBoundToTheirName(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	if _, err := Protect("github", passwordInputs, map[string]any{"token": "hunter2"}); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := Load(Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a secret copied to another name must not be decrypted
+	store.Secrets["gitlab.token"] = store.Secrets["github.token"]
+	if _, err := store.Get("gitlab.token"); err == nil {
+		t.Error("expected an error for a secret moved to another name")
+	}
+
+	renamed, err := Rename("github", "gh", map[string]any{"token": Reference("github.token")})
+	if err != nil {
+		t.Fatalf("rename: %v", err)
+	}
+
+	revealed, err := Reveal(renamed)
+	if err != nil {
+		t.Fatalf("reveal: %v", err)
+	}
+
+	if revealed["token"] != "hunter2" {
+		t.Errorf("token = %v, want the secret of the renamed extension", revealed["token"])
+	}
+}
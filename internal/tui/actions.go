@@ -0,0 +1,428 @@
+package tui
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/acarl005/stripansi"
+	"github.com/atotto/clipboard"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/muesli/termenv"
+	"github.com/pomdtr/sunbeam/internal/config"
+	"github.com/pomdtr/sunbeam/internal/extensions"
+	"github.com/pomdtr/sunbeam/internal/secrets"
+	"github.com/pomdtr/sunbeam/internal/types"
+	"github.com/pomdtr/sunbeam/internal/utils"
+)
+
+// showFormMsg asks the current page to display a form, until it is submitted or dismissed.
+type showFormMsg struct {
+	form *Form
+}
+
+// preferencesChangedMsg is sent once the preferences of an extension are saved by a config action.
+type preferencesChangedMsg struct {
+	alias       string
+	preferences map[string]any
+	// next runs once the page is reloaded with the new preferences
+	next tea.Cmd
+}
+
+// dispatchMsg sends an action whose placeholders are already expanded, ex: once it is confirmed, or its missing params are filled.
+type dispatchMsg struct {
+	action types.Action
+}
+
+// loadedMsg is sent once a page shows the result of a load.
+// The action chain waiting for the page to reload continues then.
+type loadedMsg struct {
+	id int
+}
+
+// actionRunner runs the actions triggered from a page.
+// Every page uses it, so that an action type behaves the same wherever it appears.
+type actionRunner struct {
+	// title is restored as the window title once a process exits
+	title string
+	// config resolves the extensions targeted by the actions, the config file is loaded if it is empty
+	config config.Config
+	// extension runs the commands of the actions not targeting another extension
+	extension   extensions.Extension
+	preferences map[string]any
+	// reload refreshes the page, the params override the current ones.
+	// next runs once the page shows the refreshed result, it is nil if nothing follows the reload.
+	reload func(params map[string]any, next tea.Cmd) tea.Cmd
+	// template expands the placeholders of the actions
+	template templateContext
+}
+
+// Run expands the placeholders of an action, asks for its confirmation if required, then runs it.
+func (r actionRunner) Run(action types.Action) tea.Cmd {
+	action, err := r.template.expandAction(action)
+	if err != nil {
+		return errorCmd(err)
+	}
+
+	if action.Confirm != nil && action.Confirm.Required {
+		return func() tea.Msg {
+			return showConfirmationMsg{NewConfirmation(action)}
+		}
+	}
+
+	return r.dispatch(action)
+}
+
+// dispatch runs an action, once its placeholders are expanded and it is confirmed.
+func (r actionRunner) dispatch(action types.Action) tea.Cmd {
+	switch action.Type {
+	case types.ActionTypeRun:
+		return r.runCommand(action)
+	case types.ActionTypeCopy:
+		return func() tea.Msg {
+			if err := clipboard.WriteAll(action.Text); err != nil {
+				return err
+			}
+
+			if action.Notification == "" {
+				action.Notification = "Copied!"
+			}
+
+			return after(action)
+		}
+	case types.ActionTypeOpen:
+		// sunbeam exits once the target is opened, unless other actions follow
+		if len(action.Then) == 0 {
+			action.Exit = true
+		}
+
+		return func() tea.Msg {
+			if action.Url != "" {
+				if err := utils.Open(action.Url); err != nil {
+					return err
+				}
+
+				return after(action)
+			} else if action.Path != "" {
+				if err := utils.Open(fmt.Sprintf("file://%s", action.Path)); err != nil {
+					return err
+				}
+
+				return after(action)
+			} else {
+				return fmt.Errorf("invalid target")
+			}
+		}
+	case types.ActionTypeEdit:
+		return r.execProcess(exec.Command("sunbeam", "edit", action.Path), action)
+	case types.ActionTypeExec:
+		cmd := exec.Command("sh", "-c", action.Command)
+		dir, err := resolveDir(action.Dir)
+		if err != nil {
+			return errorCmd(err)
+		}
+		cmd.Dir = dir
+
+		return r.execProcess(cmd, action)
+	case types.ActionTypeConfig:
+		return r.configure(action)
+	case types.ActionTypeHttp:
+		return r.request(action)
+	case types.ActionTypeReload:
+		params := make(map[string]any)
+		for name, param := range action.Params {
+			params[name] = param.Value
+		}
+
+		// the chain continues once the page is reloaded
+		action.Reload = false
+		return r.reload(params, afterCmd(action))
+	case types.ActionTypeExit:
+		return ExitCmd
+	default:
+		return nil
+	}
+}
+
+// after returns the message following a successful action: its notification, then the next action of the chain.
+// The reload or exit requested by the action happen once the chain completes.
+func after(action types.Action) tea.Msg {
+	var cmds []tea.Cmd
+	if action.Notification != "" {
+		cmds = append(cmds, func() tea.Msg {
+			return ShowNotificationMsg{action.Notification}
+		})
+	}
+
+	var last *types.Action
+	if action.Reload {
+		last = &types.Action{Type: types.ActionTypeReload}
+	} else if action.Exit {
+		last = &types.Action{Type: types.ActionTypeExit}
+	}
+
+	if len(action.Then) > 0 {
+		// the chain of the next action runs before the rest of this one
+		next := action.Then[0]
+		next.Then = append(append([]types.Action{}, next.Then...), action.Then[1:]...)
+		if last != nil {
+			next.Then = append(next.Then, *last)
+		}
+
+		cmds = append(cmds, func() tea.Msg {
+			return next
+		})
+	} else if last != nil {
+		cmds = append(cmds, func() tea.Msg {
+			return *last
+		})
+	}
+
+	switch len(cmds) {
+	case 0:
+		return nil
+	case 1:
+		return cmds[0]()
+	default:
+		return tea.Sequence(cmds...)()
+	}
+}
+
+// afterCmd returns the command sending the message following an action, or nil if nothing follows it.
+func afterCmd(action types.Action) tea.Cmd {
+	if action.Notification == "" && len(action.Then) == 0 && !action.Reload && !action.Exit {
+		return nil
+	}
+
+	return func() tea.Msg {
+		return after(action)
+	}
+}
+
+// terminalCommand runs a command in the terminal, and records its stderr.
+// The terminal is cleared once the command exits, so the recorded output explains why it failed.
+type terminalCommand struct {
+	*exec.Cmd
+	stderr bytes.Buffer
+}
+
+func (c *terminalCommand) SetStdin(r io.Reader) {
+	if c.Stdin == nil {
+		c.Stdin = r
+	}
+}
+
+func (c *terminalCommand) SetStdout(w io.Writer) {
+	if c.Stdout == nil {
+		c.Stdout = w
+	}
+}
+
+func (c *terminalCommand) SetStderr(w io.Writer) {
+	if c.Stderr == nil {
+		c.Stderr = io.MultiWriter(w, &c.stderr)
+	}
+}
+
+func (r actionRunner) execProcess(cmd *exec.Cmd, action types.Action) tea.Cmd {
+	command := &terminalCommand{Cmd: cmd}
+	return tea.Exec(command, func(err error) tea.Msg {
+		termenv.DefaultOutput().SetWindowTitle(r.title)
+		if err != nil {
+			if stderr := strings.TrimSpace(stripansi.Strip(command.stderr.String())); stderr != "" {
+				return fmt.Errorf("command failed: %s", stderr)
+			}
+
+			return err
+		}
+
+		return after(action)
+	})
+}
+
+func (r actionRunner) loadConfig() (config.Config, error) {
+	if r.config.Extensions != nil {
+		return r.config, nil
+	}
+
+	return config.Load(config.Path)
+}
+
+func (r actionRunner) runCommand(action types.Action) tea.Cmd {
+	extension, preferences := r.extension, r.preferences
+	if action.Extension != "" && action.Extension != r.extension.Alias {
+		cfg, err := r.loadConfig()
+		if err != nil {
+			return errorCmd(err)
+		}
+
+		extension, preferences, err = loadExtension(cfg, action.Extension)
+		if err != nil {
+			return errorCmd(err)
+		}
+
+		if form := NewPreferencesForm(cfg, extension, preferences, func(map[string]any) tea.Msg {
+			return dispatchMsg{action}
+		}); form != nil {
+			return showFormCmd(form)
+		}
+	}
+
+	command, ok := extension.Command(action.Command)
+	if !ok {
+		return errorCmd(fmt.Errorf("command %s not found", action.Command))
+	}
+
+	missingParams := FindMissingInputs(command.Params, action.Params)
+	for _, param := range missingParams {
+		if !param.Required {
+			continue
+		}
+
+		return showFormCmd(NewForm(func(values map[string]any) tea.Msg {
+			params := make(map[string]types.Param)
+			for k, v := range action.Params {
+				params[k] = v
+			}
+
+			for k, v := range values {
+				params[k] = types.Param{
+					Value: v,
+				}
+			}
+
+			action.Params = params
+			return dispatchMsg{action}
+		}, missingParams...))
+	}
+
+	input := types.Payload{
+		Command:     command.Name,
+		Params:      make(map[string]any),
+		Preferences: preferences,
+	}
+
+	for k, v := range action.Params {
+		input.Params[k] = v.Value
+	}
+
+	switch command.Mode {
+	case types.CommandModeSearch, types.CommandModeFilter, types.CommandModeDetail:
+		// the rest of the chain runs in the new page, once the output of the command is shown
+		action.Reload, action.Exit = false, false
+		runner := NewRunner(extension, input)
+		runner.onLoaded = afterCmd(action)
+		return PushPageCmd(runner)
+	case types.CommandModeSilent:
+		return func() tea.Msg {
+			if err := extension.Run(input); err != nil {
+				return PushPageMsg{NewErrorPage(err)}
+			}
+
+			return after(action)
+		}
+	case types.CommandModeTTY:
+		cmd, err := extension.Cmd(input)
+		if err != nil {
+			return errorCmd(err)
+		}
+
+		return tea.ExecProcess(cmd, func(err error) tea.Msg {
+			termenv.DefaultOutput().SetWindowTitle(r.title)
+			if err != nil {
+				return PushPageMsg{NewErrorPage(err)}
+			}
+
+			return after(action)
+		})
+	default:
+		return errorCmd(fmt.Errorf("invalid command mode: %s", command.Mode))
+	}
+}
+
+// configure shows a form editing the preferences of an extension.
+func (r actionRunner) configure(action types.Action) tea.Cmd {
+	alias := action.Extension
+	if alias == "" {
+		alias = r.extension.Alias
+	}
+
+	cfg, err := r.loadConfig()
+	if err != nil {
+		return errorCmd(err)
+	}
+
+	extensionConfig, ok := cfg.Extensions[alias]
+	if !ok {
+		return errorCmd(fmt.Errorf("extension %s not found", alias))
+	}
+
+	extension, err := extensions.LoadExtension(extensionConfig.Origin)
+	if err != nil {
+		return errorCmd(fmt.Errorf("failed to load extension %s", alias))
+	}
+	extension.Alias = alias
+
+	preferences, err := secrets.Reveal(extensionConfig.Preferences)
+	if err != nil {
+		return errorCmd(err)
+	}
+
+	inputs := make([]types.Input, 0)
+	for _, input := range extension.Manifest.Preferences {
+		input.Default = preferences[input.Name]
+		input.Required = true
+		inputs = append(inputs, input)
+	}
+
+	return showFormCmd(NewForm(func(values map[string]any) tea.Msg {
+		if err := savePreferences(cfg, alias, extension.Manifest.Preferences, values); err != nil {
+			return err
+		}
+
+		return preferencesChangedMsg{
+			alias:       alias,
+			preferences: cfg.Extensions[alias].Preferences,
+			next:        afterCmd(action),
+		}
+	}, inputs...))
+}
+
+// resolveDir returns the absolute path of the working directory of an exec action.
+func resolveDir(dir string) (string, error) {
+	if strings.HasPrefix(dir, "~") {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+
+		dir = filepath.Join(homeDir, strings.TrimPrefix(dir, "~"))
+	}
+
+	if filepath.IsAbs(dir) {
+		return dir, nil
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(wd, dir), nil
+}
+
+func showFormCmd(form *Form) tea.Cmd {
+	return func() tea.Msg {
+		return showFormMsg{form}
+	}
+}
+
+func errorCmd(err error) tea.Cmd {
+	return func() tea.Msg {
+		return err
+	}
+}
@@ -0,0 +1,237 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/muesli/termenv"
+	"github.com/pomdtr/sunbeam/internal/catalog"
+	"github.com/pomdtr/sunbeam/internal/config"
+	"github.com/pomdtr/sunbeam/internal/types"
+)
+
+// CatalogPage lists the extensions of a catalog, and installs the selected one.
+type CatalogPage struct {
+	width, height int
+	source        string
+	err           *Detail
+	list          *List
+	confirmation  *Confirmation
+	// onLoaded continues the action chain waiting for the catalog to reload
+	onLoaded tea.Cmd
+}
+
+func NewCatalogPage(source string) *CatalogPage {
+	list := NewList()
+	list.SetShowDetail(true)
+	list.SetEmptyText("No extensions")
+
+	return &CatalogPage{
+		source: source,
+		list:   list,
+	}
+}
+
+func (c *CatalogPage) Init() tea.Cmd {
+	termenv.DefaultOutput().SetWindowTitle("Extension Catalog")
+	return tea.Batch(c.list.Init(), c.list.SetIsLoading(true), c.Reload)
+}
+
+// catalogItemsMsg carries the extensions of the catalog, they are applied to the list by Update.
+type catalogItemsMsg struct {
+	items []types.ListItem
+}
+
+func (c *CatalogPage) Reload() tea.Msg {
+	cat, err := catalog.Load(c.source)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.Load(config.Path)
+	if err != nil {
+		return err
+	}
+
+	installed := make(map[string]bool)
+	for _, extensionConfig := range cfg.Extensions {
+		installed[extensionConfig.Origin] = true
+	}
+
+	items := make([]types.ListItem, 0, len(cat.Extensions))
+	for _, entry := range cat.Extensions {
+		items = append(items, catalogListItem(entry, installed[entry.Origin]))
+	}
+
+	return catalogItemsMsg{items: items}
+}
+
+func catalogListItem(entry catalog.Entry, installed bool) types.ListItem {
+	installCmd := fmt.Sprintf("sunbeam extension install --alias %s %s", shellQuote(entry.Name), shellQuote(entry.Origin))
+
+	var markdown strings.Builder
+	fmt.Fprintf(&markdown, "# %s\n\n", entry.Title)
+	if entry.Description != "" {
+		fmt.Fprintf(&markdown, "%s\n\n", entry.Description)
+	}
+
+	if len(entry.Commands) > 0 {
+		markdown.WriteString("## Commands\n\n")
+		for _, command := range entry.Commands {
+			fmt.Fprintf(&markdown, "- `%s`: %s\n", command.Name, command.Title)
+		}
+		markdown.WriteString("\n")
+	}
+
+	if len(entry.Preferences) > 0 {
+		markdown.WriteString("## Preferences\n\n")
+		for _, preference := range entry.Preferences {
+			fmt.Fprintf(&markdown, "- `%s`: %s\n", preference.Name, preference.Title)
+		}
+		markdown.WriteString("\n")
+	}
+
+	var accessories []string
+	if entry.Version != "" {
+		accessories = append(accessories, entry.Version)
+	}
+
+	var actions []types.Action
+	if installed {
+		accessories = append(accessories, "Installed")
+	} else {
+		// the install command may ask for the approval of permissions, so it runs in the terminal
+		actions = append(actions, types.Action{
+			Title:   "Install",
+			Type:    types.ActionTypeExec,
+			Command: installCmd,
+			Reload:  true,
+		})
+	}
+
+	actions = append(actions, types.Action{
+		Title: "Copy Install Command",
+		Key:   "c",
+		Type:  types.ActionTypeCopy,
+		Text:  installCmd,
+		Exit:  true,
+	})
+
+	if strings.HasPrefix(entry.Origin, "http://") || strings.HasPrefix(entry.Origin, "https://") {
+		actions = append(actions, types.Action{
+			Title: "Open Origin",
+			Key:   "o",
+			Type:  types.ActionTypeOpen,
+			Url:   entry.Origin,
+		})
+	}
+
+	return types.ListItem{
+		Id:          entry.Name,
+		Title:       entry.Title,
+		Subtitle:    entry.Name,
+		Accessories: accessories,
+		Detail: types.ListItemDetail{
+			Markdown: markdown.String(),
+		},
+		Actions: actions,
+	}
+}
+
+func (c *CatalogPage) actions() actionRunner {
+	return actionRunner{
+		title:    "Extension Catalog",
+		template: pageTemplate(c.list),
+		reload: func(_ map[string]any, next tea.Cmd) tea.Cmd {
+			if next != nil {
+				c.onLoaded = next
+			}
+
+			return tea.Sequence(c.list.SetIsLoading(true), c.Reload)
+		},
+	}
+}
+
+func (c *CatalogPage) Focus() tea.Cmd {
+	termenv.DefaultOutput().SetWindowTitle("Extension Catalog")
+	return c.list.Focus()
+}
+
+func (c *CatalogPage) Blur() tea.Cmd {
+	return nil
+}
+
+func (c *CatalogPage) SetSize(width, height int) {
+	c.width, c.height = width, height
+	if c.err != nil {
+		c.err.SetSize(width, height)
+	}
+	if c.confirmation != nil {
+		c.confirmation.SetSize(width, height)
+	}
+
+	c.list.SetSize(width, height)
+}
+
+func (c *CatalogPage) Update(msg tea.Msg) (Page, tea.Cmd) {
+	// the confirmation captures the keys until it is answered, the other messages keep reaching the list
+	if keyMsg, ok := msg.(tea.KeyMsg); ok && c.confirmation != nil {
+		page, cmd := c.confirmation.Update(keyMsg)
+		c.confirmation = page.(*Confirmation)
+		return c, cmd
+	}
+
+	switch msg := msg.(type) {
+	case types.Action:
+		return c, c.actions().Run(msg)
+	case dispatchMsg:
+		c.confirmation = nil
+		return c, c.actions().dispatch(msg.action)
+	case showConfirmationMsg:
+		c.confirmation = msg.confirmation
+		c.confirmation.SetSize(c.width, c.height)
+		return c, c.confirmation.Init()
+	case dismissConfirmationMsg:
+		c.confirmation = nil
+		return c, nil
+	case catalogItemsMsg:
+		c.list.SetItems(msg.items...)
+
+		next := c.onLoaded
+		c.onLoaded = nil
+		return c, tea.Batch(c.list.SetIsLoading(false), next)
+	case error:
+		// the action chain stops at the first error
+		c.onLoaded = nil
+		c.err = NewErrorPage(msg)
+		c.err.SetSize(c.width, c.height)
+		return c, c.err.Init()
+	}
+
+	if c.err != nil {
+		page, cmd := c.err.Update(msg)
+		c.err = page.(*Detail)
+		return c, cmd
+	}
+
+	page, cmd := c.list.Update(msg)
+	c.list = page.(*List)
+	return c, cmd
+}
+
+func (c *CatalogPage) View() string {
+	if c.confirmation != nil {
+		return c.confirmation.View()
+	}
+
+	if c.err != nil {
+		return c.err.View()
+	}
+
+	return c.list.View()
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,105 @@
+package tui
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/pomdtr/sunbeam/internal/config"
+)
+
+func TestShellQuote(t *testing.T) {
+	for _, tc := range []struct {
+		input string
+		want  string
+	}{
+		{input: "", want: `''`},
+		{input: "github", want: `'github'`},
+		{input: "https://github.com/pomdtr/sunbeam-github", want: `'https://github.com/pomdtr/sunbeam-github'`},
+		{input: "$(rm -rf ~)", want: `'$(rm -rf ~)'`},
+		{input: "it's", want: `'it'\''s'`},
+	} {
+		got := shellQuote(tc.input)
+		if got != tc.want {
+			t.Errorf("shellQuote(%q) = %s, want %s", tc.input, got, tc.want)
+		}
+
+		// the quoted string must be read back as a single word by the shell
+		output, err := exec.Command("sh", "-c", "printf %s "+got).Output()
+		if err != nil {
+			t.Fatalf("sh: %v", err)
+		}
+
+		if string(output) != tc.input {
+			t.Errorf("sh read %q back as %q", tc.input, output)
+		}
+	}
+}
+
+func TestCatalogReload(t *testing.T) {
+	dir := t.TempDir()
+	catalogPath := filepath.Join(dir, "catalog.json")
+	if err := os.WriteFile(catalogPath, []byte(`{"extensions": [
+		{"name": "github", "title": "GitHub", "origin": "https://github.com/pomdtr/sunbeam-github"},
+		{"name": "tldr", "title": "TLDR", "origin": "https://github.com/pomdtr/sunbeam-tldr"}
+	]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	previous := config.Path
+	config.Path = filepath.Join(dir, "sunbeam.json")
+	t.Cleanup(func() { config.Path = previous })
+	if err := os.WriteFile(config.Path, []byte(`{"extensions": {"github": {"origin": "https://github.com/pomdtr/sunbeam-github"}}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	page := NewCatalogPage(catalogPath)
+	page.list.SetIsLoading(true)
+
+	msg, ok := page.Reload().(catalogItemsMsg)
+	if !ok {
+		t.Fatalf("msg = %T, want a catalogItemsMsg", msg)
+	}
+
+	if len(page.list.filter.items) != 0 || !page.list.isLoading {
+		t.Fatal("the list was updated outside of Update")
+	}
+
+	if len(msg.items) != 2 {
+		t.Fatalf("len(items) = %d, want 2", len(msg.items))
+	}
+
+	if actions := msg.items[0].Actions; len(actions) == 0 || actions[0].Title == "Install" {
+		t.Errorf("installed extension actions = %+v, want no install action", actions)
+	}
+
+	if actions := msg.items[1].Actions; len(actions) == 0 || actions[0].Title != "Install" {
+		t.Errorf("extension actions = %+v, want the install action first", actions)
+	}
+
+	page.onLoaded = func() tea.Msg { return ShowNotificationMsg{Title: "Reloaded"} }
+	_, cmd := page.Update(msg)
+
+	if len(page.list.filter.items) != 2 || page.list.isLoading {
+		t.Error("the items were not applied by Update")
+	}
+
+	if page.onLoaded != nil {
+		t.Error("the pending action was not consumed")
+	}
+
+	if cmd == nil {
+		t.Fatal("the pending action was dropped")
+	}
+
+	batch, ok := cmd().(tea.BatchMsg)
+	if !ok || len(batch) != 1 {
+		t.Fatalf("cmd returned %#v, want a batch of the pending action", batch)
+	}
+
+	if notification, ok := batch[0]().(ShowNotificationMsg); !ok || notification.Title != "Reloaded" {
+		t.Errorf("pending action returned %#v, want the notification", notification)
+	}
+}
@@ -0,0 +1,110 @@
+package tui
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/pomdtr/sunbeam/internal/types"
+)
+
+// showConfirmationMsg asks the current page to display a confirmation over its content, until it is answered.
+// The page is not blurred, so its pending load or stream keeps running.
+type showConfirmationMsg struct {
+	confirmation *Confirmation
+}
+
+// dismissConfirmationMsg hides the confirmation of the current page, the action is not run.
+type dismissConfirmationMsg struct{}
+
+// Confirmation asks the user to confirm an action.
+// Once confirmed, the action is sent to the page displaying it, which runs it without expanding its placeholders again.
+type Confirmation struct {
+	width, height int
+	action        types.Action
+	yes           bool
+}
+
+func NewConfirmation(action types.Action) *Confirmation {
+	return &Confirmation{
+		action: action,
+	}
+}
+
+func (c *Confirmation) Init() tea.Cmd {
+	return nil
+}
+
+func (c *Confirmation) Focus() tea.Cmd {
+	return nil
+}
+
+func (c *Confirmation) Blur() tea.Cmd {
+	return nil
+}
+
+func (c *Confirmation) SetSize(width, height int) {
+	c.width, c.height = width, height
+}
+
+func (c *Confirmation) Update(msg tea.Msg) (Page, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return c, nil
+	}
+
+	switch keyMsg.String() {
+	case "y":
+		return c, c.confirm()
+	case "n", "esc":
+		return c, c.dismiss
+	case "left", "right", "tab", "shift+tab", "h", "l":
+		c.yes = !c.yes
+	case "enter":
+		if c.yes {
+			return c, c.confirm()
+		}
+
+		return c, c.dismiss
+	}
+
+	return c, nil
+}
+
+func (c *Confirmation) confirm() tea.Cmd {
+	action := c.action
+	action.Confirm = nil
+
+	return func() tea.Msg {
+		return dispatchMsg{action}
+	}
+}
+
+func (c *Confirmation) dismiss() tea.Msg {
+	return dismissConfirmationMsg{}
+}
+
+func (c *Confirmation) View() string {
+	question := c.action.Confirm.Question
+	if question == "" {
+		question = fmt.Sprintf("Are you sure you want to run %s?", ActionTitle(c.action))
+	}
+
+	buttonStyle := lipgloss.NewStyle().Padding(0, 2)
+	selectedStyle := lipgloss.NewStyle().Padding(0, 2).Foreground(lipgloss.Color("13")).Bold(true)
+
+	yes, no := buttonStyle.Render("[ Yes ]"), selectedStyle.Render("[ No ]")
+	if c.yes {
+		yes, no = selectedStyle.Render("[ Yes ]"), buttonStyle.Render("[ No ]")
+	}
+
+	dialog := lipgloss.JoinVertical(
+		lipgloss.Center,
+		question,
+		"",
+		lipgloss.JoinHorizontal(lipgloss.Top, yes, no),
+	)
+	dialog = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).Padding(1, 2).Render(dialog)
+
+	return lipgloss.Place(c.width, c.height, lipgloss.Center, lipgloss.Center, dialog)
+}
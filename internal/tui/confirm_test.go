@@ -0,0 +1,93 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/pomdtr/sunbeam/internal/extensions"
+	"github.com/pomdtr/sunbeam/internal/types"
+)
+
+func keyMsg(key string) tea.KeyMsg {
+	if key == "esc" {
+		return tea.KeyMsg{Type: tea.KeyEsc}
+	}
+
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)}
+}
+
+func TestConfirmationKeepsLoading(t *testing.T) {
+	for _, tc := range []struct {
+		key string
+		run bool
+	}{
+		{key: "y", run: true},
+		{key: "n"},
+		{key: "esc"},
+	} {
+		t.Run(tc.key, func(t *testing.T) {
+			extension := extensions.Extension{
+				Type: extensions.ExtensionTypeLocal,
+				Manifest: types.Manifest{
+					Commands: []types.CommandSpec{{Name: "list", Mode: types.CommandModeFilter}},
+				},
+			}
+			runner := NewRunner(extension, types.Payload{Command: "list"})
+			runner.SetSize(80, 24)
+
+			// a load is in flight, and an action chain waits for it
+			var canceled bool
+			runner.cancel = func() { canceled = true }
+			runner.onLoaded = func() tea.Msg { return ShowNotificationMsg{Title: "Loaded"} }
+
+			_, cmd := runner.Update(types.Action{
+				Type:    types.ActionTypeExit,
+				Confirm: &types.Confirm{Required: true, Question: "Exit sunbeam?"},
+			})
+
+			msg, ok := cmd().(showConfirmationMsg)
+			if !ok {
+				t.Fatalf("msg = %#v, want a showConfirmationMsg", msg)
+			}
+			runner.Update(msg)
+
+			if !strings.Contains(runner.View(), "Exit sunbeam?") {
+				t.Errorf("view does not show the question:\n%s", runner.View())
+			}
+
+			if canceled || runner.onLoaded == nil {
+				t.Fatal("showing the confirmation canceled the pending load")
+			}
+
+			// the keys are captured by the confirmation
+			_, cmd = runner.Update(keyMsg(tc.key))
+			if cmd == nil {
+				t.Fatalf("%s: the confirmation did not answer", tc.key)
+			}
+
+			var ran bool
+			if _, cmd = runner.Update(cmd()); cmd != nil {
+				_, ran = cmd().(ExitMsg)
+			}
+
+			if runner.confirmation != nil {
+				t.Errorf("%s: the confirmation is still shown", tc.key)
+			}
+
+			if ran != tc.run {
+				t.Errorf("%s: the action ran: %t, want %t", tc.key, ran, tc.run)
+			}
+
+			// the result of the load still continues the chain
+			_, cmd = runner.Update(loadedMsg{id: runner.loadID})
+			if cmd == nil {
+				t.Fatal("the action chain did not continue once loaded")
+			}
+
+			if notification, ok := cmd().(ShowNotificationMsg); !ok || notification.Title != "Loaded" {
+				t.Errorf("chain msg = %#v, want the pending action", notification)
+			}
+		})
+	}
+}
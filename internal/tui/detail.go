@@ -31,6 +31,9 @@ type Detail struct {
 
 	Style    lipgloss.Style
 	Markdown bool
+
+	// onLoaded continues the action chain once the page is shown
+	onLoaded tea.Cmd
 }
 
 func AnsiStyle() ansi.StyleConfig {
@@ -81,7 +84,9 @@ func NewDetail(text string, actions ...types.Action) *Detail {
 }
 
 func (d *Detail) Init() tea.Cmd {
-	return nil
+	next := d.onLoaded
+	d.onLoaded = nil
+	return next
 }
 
 func (d *Detail) Focus() tea.Cmd {
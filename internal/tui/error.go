@@ -1,6 +1,10 @@
 package tui
 
 import (
+	"errors"
+	"fmt"
+
+	"github.com/pomdtr/sunbeam/internal/extensions"
 	"github.com/pomdtr/sunbeam/internal/types"
 )
 
@@ -12,9 +16,30 @@ func NewErrorPage(err error, additionalActions ...types.Action) *Detail {
 		Text:  err.Error(),
 		Exit:  true,
 	})
+
+	var missingErr *extensions.MissingRequirementsError
+	if errors.As(err, &missingErr) {
+		for _, requirement := range missingErr.Requirements {
+			if requirement.Link == "" {
+				continue
+			}
+
+			actions = append(actions, types.Action{
+				Title: fmt.Sprintf("Install %s", requirement.Name),
+				Type:  types.ActionTypeOpen,
+				Url:   requirement.Link,
+			})
+		}
+	}
 	actions = append(actions, additionalActions...)
 
-	detail := NewDetail(err.Error(), actions...)
+	text := err.Error()
+	var timeoutErr *extensions.TimeoutError
+	if errors.As(err, &timeoutErr) {
+		text = fmt.Sprintf("%s\n\nThe timeout of the extension can be increased with the timeout field of its config.", text)
+	}
+
+	detail := NewDetail(text, actions...)
 
 	return detail
 }
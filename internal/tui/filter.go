@@ -16,6 +16,11 @@ type FilterItem interface {
 	ID() string
 }
 
+// FallbackItem is implemented by the items listed after the matches of any query.
+type FallbackItem interface {
+	Fallback() bool
+}
+
 type Filter struct {
 	minIndex      int
 	Width, Height int
@@ -82,17 +87,21 @@ func (f *Filter) FilterItems(query string) {
 		f.filtered = f.items
 	} else {
 		f.filtered = make([]FilterItem, 0)
+		var fallbacks []FilterItem
 		for i := 0; i < len(f.items); i++ {
 			filterValue := f.items[i].FilterValue()
 			score := fzf.Score(filterValue, query)
 			if score > 0 {
 				f.filtered = append(f.filtered, f.items[i])
+			} else if item, ok := f.items[i].(FallbackItem); ok && item.Fallback() {
+				fallbacks = append(fallbacks, f.items[i])
 			}
 		}
 
 		sort.SliceStable(f.filtered, func(i, j int) bool {
 			return fzf.Score(f.filtered[i].FilterValue(), query) > fzf.Score(f.filtered[j].FilterValue(), query)
 		})
+		f.filtered = append(f.filtered, fallbacks...)
 	}
 
 	if f.cursor >= len(f.filtered) {
@@ -102,9 +111,17 @@ func (f *Filter) FilterItems(query string) {
 
 func (f *Filter) Select(id string) {
 	for i, item := range f.filtered {
-		if item.ID() == id {
-			f.cursor = i
+		if item.ID() != id {
+			continue
+		}
+
+		f.cursor = i
+		if f.cursor < f.minIndex {
+			f.minIndex = f.cursor
+		} else if f.cursor >= f.minIndex+f.nbVisibleItems() {
+			f.minIndex = f.cursor - f.nbVisibleItems() + 1
 		}
+		return
 	}
 }
 
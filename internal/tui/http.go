@@ -0,0 +1,98 @@
+package tui
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/atotto/clipboard"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/pomdtr/sunbeam/internal/types"
+)
+
+var httpClient = &http.Client{
+	Timeout: 30 * time.Second,
+}
+
+// writeClipboard copies the responses of the http actions, it is replaced in tests.
+var writeClipboard = clipboard.WriteAll
+
+// request performs the request of an http action, then shows, copies or notifies its response.
+// Responses with an error status stop the chain of the action.
+func (r actionRunner) request(action types.Action) tea.Cmd {
+	return func() tea.Msg {
+		method := action.Method
+		if method == "" {
+			method = http.MethodGet
+		}
+
+		var body io.Reader
+		if action.Body != "" {
+			body = strings.NewReader(action.Body)
+		}
+
+		req, err := http.NewRequest(strings.ToUpper(method), action.Url, body)
+		if err != nil {
+			return fmt.Errorf("invalid request: %w", err)
+		}
+
+		for name, value := range action.Headers {
+			req.Header.Set(name, value)
+		}
+
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("request failed: %w", err)
+		}
+		defer resp.Body.Close()
+
+		respBody, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read response: %w", err)
+		}
+
+		if resp.StatusCode >= 400 {
+			return fmt.Errorf("request failed: %s\n\n%s", resp.Status, respBody)
+		}
+
+		switch action.Output {
+		case types.HttpOutputCopy:
+			if err := writeClipboard(string(respBody)); err != nil {
+				return err
+			}
+
+			if action.Notification == "" {
+				action.Notification = "Copied!"
+			}
+
+			return after(action)
+		case types.HttpOutputNotify:
+			if action.Notification == "" {
+				action.Notification = resp.Status
+			}
+
+			return after(action)
+		default:
+			// json responses are indented, to be readable
+			var indented bytes.Buffer
+			if err := json.Indent(&indented, respBody, "", "  "); err == nil {
+				respBody = indented.Bytes()
+			}
+
+			page := NewDetail(fmt.Sprintf("%s %s\n\n%s", resp.Proto, resp.Status, respBody), types.Action{
+				Title: "Copy Body",
+				Type:  types.ActionTypeCopy,
+				Text:  string(respBody),
+			})
+
+			// the rest of the chain runs in the new page
+			action.Reload, action.Exit = false, false
+			page.onLoaded = afterCmd(action)
+			return PushPageMsg{Page: page}
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package tui
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/atotto/clipboard"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/pomdtr/sunbeam/internal/types"
+)
+
+func TestRequest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get("Accept") == "application/json" {
+			fmt.Fprintf(w, `{"method":%q,"token":%q,"body":%s}`, r.Method, r.Header.Get("Authorization"), body)
+			return
+		}
+
+		io.WriteString(w, "hello")
+	}))
+	defer server.Close()
+
+	var copied string
+	writeClipboard = func(text string) error {
+		copied = text
+		return nil
+	}
+	defer func() { writeClipboard = clipboard.WriteAll }()
+
+	for _, tc := range []struct {
+		name   string
+		action types.Action
+		// check inspects the message returned by the request
+		check func(t *testing.T, msg tea.Msg)
+	}{
+		{
+			name: "detail",
+			action: types.Action{
+				Method:       "post",
+				Headers:      map[string]string{"Accept": "application/json", "Authorization": "Bearer token"},
+				Body:         `{"name":"sunbeam"}`,
+				Notification: "Done",
+			},
+			check: func(t *testing.T, msg tea.Msg) {
+				push, ok := msg.(PushPageMsg)
+				if !ok {
+					t.Fatalf("msg = %#v, want a PushPageMsg", msg)
+				}
+
+				detail, ok := push.Page.(*Detail)
+				if !ok {
+					t.Fatalf("page = %T, want *Detail", push.Page)
+				}
+
+				for _, want := range []string{"200 OK", `"method": "POST"`, `"token": "Bearer token"`, `"name": "sunbeam"`} {
+					if !strings.Contains(detail.text, want) {
+						t.Errorf("detail does not contain %q:\n%s", want, detail.text)
+					}
+				}
+
+				// the chain continues once the page is pushed
+				init := push.Page.Init()
+				if init == nil {
+					t.Fatal("the notification was dropped")
+				}
+
+				if notification, ok := init().(ShowNotificationMsg); !ok || notification.Title != "Done" {
+					t.Errorf("init msg = %#v, want the notification", notification)
+				}
+
+				if push.Page.Init() != nil {
+					t.Error("the chain continues every time the page is shown")
+				}
+			},
+		},
+		{
+			name:   "notify",
+			action: types.Action{Output: types.HttpOutputNotify},
+			check: func(t *testing.T, msg tea.Msg) {
+				if notification, ok := msg.(ShowNotificationMsg); !ok || notification.Title != "200 OK" {
+					t.Errorf("msg = %#v, want the response status", msg)
+				}
+			},
+		},
+		{
+			name:   "copy",
+			action: types.Action{Output: types.HttpOutputCopy},
+			check: func(t *testing.T, msg tea.Msg) {
+				if notification, ok := msg.(ShowNotificationMsg); !ok || notification.Title != "Copied!" {
+					t.Errorf("msg = %#v, want the copied notification", msg)
+				}
+
+				if copied != "hello" {
+					t.Errorf("clipboard = %q, want the response body", copied)
+				}
+			},
+		},
+		{
+			name: "error status",
+			action: types.Action{
+				Url:          "/missing",
+				Output:       types.HttpOutputNotify,
+				Notification: "Done",
+				Then:         []types.Action{{Type: types.ActionTypeReload}},
+			},
+			check: func(t *testing.T, msg tea.Msg) {
+				err, ok := msg.(error)
+				if !ok {
+					t.Fatalf("msg = %#v, want an error stopping the chain", msg)
+				}
+
+				if !strings.Contains(err.Error(), "404") || !strings.Contains(err.Error(), "not found") {
+					t.Errorf("error = %q, want the status and body", err)
+				}
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			action := tc.action
+			action.Type = types.ActionTypeHttp
+			action.Url = server.URL + action.Url
+
+			tc.check(t, actionRunner{}.request(action)())
+		})
+	}
+}
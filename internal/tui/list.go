@@ -225,6 +225,20 @@ func (c List) Selection() (types.ListItem, bool) {
 	return types.ListItem(item), true
 }
 
+// Select moves the cursor to the item with the given id, if it is still listed.
+func (c *List) Select(id string) {
+	c.filter.Select(id)
+
+	if selection := c.filter.Selection(); selection != nil {
+		listItem := selection.(ListItem)
+		c.statusBar.SetActions(listItem.Actions...)
+
+		if c.showDetail {
+			c.updateViewport(listItem.Detail)
+		}
+	}
+}
+
 func (c *List) SetItems(items ...types.ListItem) {
 	filterItems := make([]FilterItem, len(items))
 	for i, item := range items {
@@ -238,6 +252,27 @@ func (c *List) SetItems(items ...types.ListItem) {
 	}
 }
 
+func (c *List) AddItems(items ...types.ListItem) {
+	hadSelection := c.filter.Selection() != nil
+
+	filterItems := make([]FilterItem, 0, len(c.filter.items)+len(items))
+	filterItems = append(filterItems, c.filter.items...)
+	for _, item := range items {
+		filterItems = append(filterItems, ListItem(item))
+	}
+
+	c.filter.SetItems(filterItems...)
+
+	if c.OnQueryChange == nil {
+		c.FilterItems(c.Query())
+		return
+	}
+
+	if selection := c.filter.Selection(); selection != nil && !hadSelection {
+		c.statusBar.SetActions(selection.(ListItem).Actions...)
+	}
+}
+
 func (c *List) SetIsLoading(isLoading bool) tea.Cmd {
 	c.isLoading = isLoading
 	if isLoading {
@@ -23,6 +23,11 @@ func (i ListItem) FilterValue() string {
 	return strings.Trim(strings.Join(keywords, " "), " ")
 }
 
+// Fallback reports whether the primary action of the item refers to the query, so that it stays listed whatever the user types.
+func (i ListItem) Fallback() bool {
+	return len(i.Actions) > 0 && usesQuery(i.Actions[:1])
+}
+
 func RenderItem(title string, subtitle string, accessories []string, width int, selected bool) string {
 	if width == 0 {
 		return ""
@@ -2,6 +2,7 @@ package tui
 
 import (
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/pomdtr/sunbeam/internal/extensions"
 )
 
 func PopPageCmd() tea.Msg {
@@ -36,6 +37,9 @@ type Page interface {
 type ExitMsg struct {
 }
 
+// SourceChangedMsg is sent to the current page when the sources of the extension it runs change.
+type SourceChangedMsg struct{}
+
 func ExitCmd() tea.Msg {
 	return ExitMsg{}
 }
@@ -151,9 +155,30 @@ func (m *Paginator) Pop() tea.Cmd {
 }
 
 func Draw(page Page) error {
+	defer extensions.StopProcesses()
+
+	paginator := NewPaginator(page)
+	p := tea.NewProgram(paginator, tea.WithAltScreen())
+
+	_, err := p.Run()
+	return err
+}
+
+// DrawDev draws the page, and sends a SourceChangedMsg to the current page each time a value is received on changes.
+func DrawDev(page Page, changes <-chan struct{}) error {
+	defer extensions.StopProcesses()
+
 	paginator := NewPaginator(page)
 	p := tea.NewProgram(paginator, tea.WithAltScreen())
 
+	go func() {
+		for range changes {
+			// the persistent processes run the previous version of the sources
+			extensions.StopProcesses()
+			p.Send(SourceChangedMsg{})
+		}
+	}()
+
 	_, err := p.Run()
 	return err
 }
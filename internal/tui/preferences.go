@@ -0,0 +1,112 @@
+package tui
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/pomdtr/sunbeam/internal/config"
+	"github.com/pomdtr/sunbeam/internal/extensions"
+	"github.com/pomdtr/sunbeam/internal/secrets"
+	"github.com/pomdtr/sunbeam/internal/types"
+)
+
+// loadExtension loads an installed extension, and its preferences overridden by the environment.
+func loadExtension(cfg config.Config, alias string) (extensions.Extension, map[string]any, error) {
+	extensionConfig, ok := cfg.Extensions[alias]
+	if !ok {
+		return extensions.Extension{}, nil, fmt.Errorf("extension %s not found", alias)
+	}
+
+	extension, err := extensions.LoadExtension(extensionConfig.Origin)
+	if err != nil {
+		return extensions.Extension{}, nil, fmt.Errorf("failed to load extension: %w", err)
+	}
+	extension.Config = extensionConfig
+	extension.Alias = alias
+
+	preferences := make(map[string]any)
+	for name, value := range extensionConfig.Preferences {
+		preferences[name] = value
+	}
+
+	envs, err := ExtractPreferencesFromEnv(alias, extension)
+	if err != nil {
+		return extensions.Extension{}, nil, err
+	}
+	for name, value := range envs {
+		preferences[name] = value
+	}
+
+	return extension, preferences, nil
+}
+
+// NewPreferencesForm returns a form asking for the required preferences of the extension that are missing,
+// or nil if none are missing. On submit, the answers are saved to the config of the installed extension,
+// and the submitMsg function is called with the completed preferences.
+func NewPreferencesForm(cfg config.Config, extension extensions.Extension, preferences map[string]any, submitMsg func(map[string]any) tea.Msg) *Form {
+	var inputs []types.Input
+	for _, input := range FindMissingPreferences(extension.Manifest.Preferences, preferences) {
+		if input.Required {
+			inputs = append(inputs, input)
+		}
+	}
+
+	if len(inputs) == 0 {
+		return nil
+	}
+
+	return NewForm(func(values map[string]any) tea.Msg {
+		if err := savePreferences(cfg, extension.Alias, inputs, values); err != nil {
+			return err
+		}
+
+		return submitMsg(mergePreferences(preferences, values))
+	}, inputs...)
+}
+
+// savePreferences merges the answers into the preferences of the extension.
+// They are only persisted if the extension is installed, extensions run in dev mode keep them in memory.
+func savePreferences(cfg config.Config, alias string, inputs []types.Input, values map[string]any) error {
+	extensionConfig, ok := cfg.Extensions[alias]
+	if !ok {
+		return nil
+	}
+
+	installedConfig, err := config.Load(config.Path)
+	if err != nil {
+		return err
+	}
+
+	if installed, ok := installedConfig.Extensions[alias]; ok && installed.Origin == extensionConfig.Origin {
+		protected, err := secrets.Protect(alias, inputs, values)
+		if err != nil {
+			return err
+		}
+
+		installed.Preferences = mergePreferences(installed.Preferences, protected)
+		installedConfig.Extensions[alias] = installed
+		if err := installedConfig.Save(); err != nil {
+			return err
+		}
+
+		values = protected
+	}
+
+	extensionConfig.Preferences = mergePreferences(extensionConfig.Preferences, values)
+	cfg.Extensions[alias] = extensionConfig
+
+	return nil
+}
+
+func mergePreferences(preferences map[string]any, values map[string]any) map[string]any {
+	merged := make(map[string]any)
+	for name, value := range preferences {
+		merged[name] = value
+	}
+
+	for name, value := range values {
+		merged[name] = value
+	}
+
+	return merged
+}
@@ -2,18 +2,14 @@ package tui
 
 import (
 	"encoding/json"
-	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"sort"
-	"strings"
 
-	"github.com/atotto/clipboard"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/muesli/termenv"
 	"github.com/pomdtr/sunbeam/internal/config"
-	"github.com/pomdtr/sunbeam/internal/extensions"
 	"github.com/pomdtr/sunbeam/internal/history"
 	"github.com/pomdtr/sunbeam/internal/types"
 	"github.com/pomdtr/sunbeam/internal/utils"
@@ -25,10 +21,13 @@ type RootList struct {
 	err           *Detail
 	list          *List
 	form          *Form
+	confirmation  *Confirmation
 
 	config    config.Config
 	history   history.History
 	generator func() (config.Config, []types.ListItem, error)
+	// onLoaded continues the action chain waiting for the list to reload
+	onLoaded tea.Cmd
 }
 
 func NewRootList(title string, history history.History, generator func() (config.Config, []types.ListItem, error)) *RootList {
@@ -55,7 +54,7 @@ func (c *RootList) Reload() tea.Msg {
 	if c.list != nil {
 		c.list.SetIsLoading(false)
 		c.list.SetItems(rootItems...)
-		return nil
+		return loadedMsg{}
 	} else {
 		c.list = NewList(rootItems...)
 		c.list.SetEmptyText("No items")
@@ -65,6 +64,21 @@ func (c *RootList) Reload() tea.Msg {
 	}
 }
 
+func (c *RootList) actions() actionRunner {
+	return actionRunner{
+		title:    c.title,
+		config:   c.config,
+		template: pageTemplate(c.list),
+		reload: func(_ map[string]any, next tea.Cmd) tea.Cmd {
+			if next != nil {
+				c.onLoaded = next
+			}
+
+			return tea.Sequence(c.list.SetIsLoading(true), c.Reload)
+		},
+	}
+}
+
 func (c *RootList) Focus() tea.Cmd {
 	termenv.DefaultOutput().SetWindowTitle(c.title)
 	return c.list.Focus()
@@ -82,6 +96,9 @@ func (c *RootList) SetSize(width, height int) {
 	if c.form != nil {
 		c.form.SetSize(width, height)
 	}
+	if c.confirmation != nil {
+		c.confirmation.SetSize(width, height)
+	}
 
 	if c.list != nil {
 		c.list.SetSize(width, height)
@@ -97,6 +114,13 @@ func (c *RootList) SetError(err error) tea.Cmd {
 }
 
 func (c *RootList) Update(msg tea.Msg) (Page, tea.Cmd) {
+	// the confirmation captures the keys until it is answered, the other messages keep reaching the list
+	if keyMsg, ok := msg.(tea.KeyMsg); ok && c.confirmation != nil {
+		page, cmd := c.confirmation.Update(keyMsg)
+		c.confirmation = page.(*Confirmation)
+		return c, cmd
+	}
+
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
 		switch msg.String() {
@@ -138,6 +162,14 @@ func (c *RootList) Update(msg tea.Msg) (Page, tea.Cmd) {
 		case "ctrl+r":
 			return c, tea.Batch(c.list.SetIsLoading(true), c.Reload)
 		}
+	case SourceChangedMsg:
+		// the error of the previous version is replaced by the one of the new version, if any
+		c.err = nil
+		if c.list == nil {
+			return c, c.Reload
+		}
+
+		return c, tea.Batch(c.list.SetIsLoading(true), c.Reload)
 	case types.Action:
 		selection, ok := c.list.Selection()
 		if !ok {
@@ -148,263 +180,37 @@ func (c *RootList) Update(msg tea.Msg) (Page, tea.Cmd) {
 			return c, c.SetError(err)
 		}
 
-		switch msg.Type {
-		case types.ActionTypeRun:
-			extensionConfig := c.config.Extensions[msg.Extension]
-			extension, err := extensions.LoadExtension(extensionConfig.Origin)
-			if err != nil {
-				return c, c.SetError(fmt.Errorf("failed to load extension: %w", err))
-			}
-
-			preferences := extensionConfig.Preferences
-			if preferences == nil {
-				preferences = make(map[string]any)
-			}
-
-			envs, err := ExtractPreferencesFromEnv(msg.Extension, extension)
-			if err != nil {
-				return c, c.SetError(err)
-			}
-			for name, value := range envs {
-				preferences[name] = value
-			}
-
-			missingPreferences := FindMissingPreferences(extension.Manifest.Preferences, preferences)
-			for _, preference := range missingPreferences {
-				if !preference.Required {
-					continue
-				}
-
-				c.form = NewForm(func(values map[string]any) tea.Msg {
-					if extensionConfig.Preferences == nil {
-						extensionConfig.Preferences = make(map[string]any)
-					}
-
-					for k, v := range values {
-						extensionConfig.Preferences[k] = v
-					}
-
-					c.config.Extensions[msg.Extension] = extensionConfig
-					if config.Path == "" {
-						return msg
-					}
-
-					if err := c.config.Save(); err != nil {
-						return err
-					}
-
-					return msg
-				}, missingPreferences...)
-
-				c.form.SetSize(c.width, c.height)
-				return c, c.form.Init()
-			}
-
-			command, ok := extension.Command(msg.Command)
-			if !ok {
-				return c, c.SetError(fmt.Errorf("command %s not found", msg.Command))
-			}
-
-			missingParams := FindMissingInputs(command.Params, msg.Params)
-			for _, param := range missingParams {
-				if !param.Required {
-					continue
-				}
-
-				c.form = NewForm(func(values map[string]any) tea.Msg {
-					params := make(map[string]types.Param)
-					for k, v := range msg.Params {
-						params[k] = v
-					}
-
-					for k, v := range values {
-						params[k] = types.Param{
-							Value: v,
-						}
-					}
-
-					return types.Action{
-						Title:     msg.Title,
-						Type:      types.ActionTypeRun,
-						Extension: msg.Extension,
-						Command:   msg.Command,
-						Params:    params,
-						Exit:      msg.Exit,
-						Reload:    msg.Reload,
-					}
-				}, missingParams...)
-
-				c.form.SetSize(c.width, c.height)
-				return c, c.form.Init()
-			}
-			c.form = nil
-
-			input := types.Payload{
-				Command:     command.Name,
-				Params:      make(map[string]any),
-				Preferences: preferences,
-			}
-
-			for k, v := range msg.Params {
-				input.Params[k] = v.Value
-			}
-
-			switch command.Mode {
-			case types.CommandModeSearch, types.CommandModeFilter, types.CommandModeDetail:
-				runner := NewRunner(extension, input)
-				return c, PushPageCmd(runner)
-			case types.CommandModeSilent:
-				return c, func() tea.Msg {
-					if err := extension.Run(input); err != nil {
-						return PushPageMsg{NewErrorPage(err)}
-					}
-
-					if msg.Exit {
-						return ExitMsg{}
-					}
-
-					return nil
-				}
-			case types.CommandModeTTY:
-				cmd, err := extension.Cmd(input)
-
-				if err != nil {
-					c.err = NewErrorPage(err)
-					c.err.SetSize(c.width, c.height)
-					return c, c.err.Init()
-				}
-
-				return c, tea.ExecProcess(cmd, func(err error) tea.Msg {
-					if err != nil {
-						return PushPageMsg{NewErrorPage(err)}
-					}
-
-					if msg.Exit {
-						return ExitMsg{}
-					}
-
-					termenv.DefaultOutput().SetWindowTitle(c.title)
-					return c.list.Focus()
-				})
-			}
-		case types.ActionTypeCopy:
-			return c, func() tea.Msg {
-				if err := clipboard.WriteAll(msg.Text); err != nil {
-					return err
-				}
-
-				if msg.Exit {
-					return ExitMsg{}
-				}
-
-				return ShowNotificationMsg{"Copied!"}
-			}
-		case types.ActionTypeEdit:
-			editCmd := exec.Command("sunbeam", "edit", msg.Path)
-			return c, tea.ExecProcess(editCmd, func(err error) tea.Msg {
-				if err != nil {
-					return err
-				}
-
-				if msg.Reload {
-					return c.Reload()
-				}
-
-				if msg.Exit {
-					return ExitMsg{}
-				}
-
-				return nil
-			})
-		case types.ActionTypeConfig:
-			extensionConfig, ok := c.config.Extensions[msg.Extension]
-			if !ok {
-				return c, c.SetError(fmt.Errorf("extension %s not found", msg.Extension))
-			}
-
-			extension, err := extensions.LoadExtension(extensionConfig.Origin)
-			if err != nil {
-				return c, c.SetError(fmt.Errorf("failed to load extension %s", msg.Extension))
-			}
-
-			inputs := make([]types.Input, 0)
-			for _, input := range extension.Manifest.Preferences {
-				input.Default = extensionConfig.Preferences[input.Name]
-				input.Required = true
-				inputs = append(inputs, input)
-			}
-
-			c.form = NewForm(func(values map[string]any) tea.Msg {
-				c.form = nil
-				extensionConfig.Preferences = values
-				c.config.Extensions[msg.Extension] = extensionConfig
-				if err := c.config.Save(); err != nil {
-					return err
-				}
-
-				return nil
-			}, inputs...)
-			c.form.SetSize(c.width, c.height)
-			return c, c.form.Init()
-		case types.ActionTypeExec:
-			cmd := exec.Command("sh", "-c", msg.Command)
-			cmd.Dir = msg.Dir
-			if strings.HasPrefix(cmd.Dir, "~") {
-				homeDir, err := os.UserHomeDir()
-				if err != nil {
-					return c, c.SetError(err)
-				}
-
-				cmd.Dir = filepath.Join(homeDir, strings.TrimPrefix(cmd.Dir, "~"))
-			}
-
-			if !filepath.IsAbs(cmd.Dir) {
-				wd, err := os.Getwd()
-				if err != nil {
-					return c, c.SetError(err)
-				}
-
-				cmd.Dir = filepath.Join(wd, cmd.Dir)
-			}
-
-			return c, tea.ExecProcess(cmd, func(err error) tea.Msg {
-				if err != nil {
-					return err
-				}
-
-				if msg.Exit {
-					return ExitMsg{}
-				}
-
-				termenv.DefaultOutput().SetWindowTitle(c.title)
-				return nil
-			})
-		case types.ActionTypeOpen:
-			return c, func() tea.Msg {
-				if msg.Url != "" {
-					if err := utils.Open(msg.Url); err != nil {
-						return err
-					}
-
-					return ExitMsg{}
-				} else if msg.Path != "" {
-					if err := utils.Open(fmt.Sprintf("file://%s", msg.Path)); err != nil {
-						return err
-					}
-
-					return ExitMsg{}
-				} else {
-					return fmt.Errorf("invalid target")
-				}
-			}
-		case types.ActionTypeReload:
-			return c, tea.Sequence(c.list.SetIsLoading(true), c.Reload)
-		case types.ActionTypeExit:
-			return c, ExitCmd
-		default:
-			return c, nil
+		c.form = nil
+		return c, c.actions().Run(msg)
+	case dispatchMsg:
+		c.form = nil
+		c.confirmation = nil
+		return c, c.actions().dispatch(msg.action)
+	case showFormMsg:
+		c.form = msg.form
+		c.form.SetSize(c.width, c.height)
+		return c, c.form.Init()
+	case showConfirmationMsg:
+		c.confirmation = msg.confirmation
+		c.confirmation.SetSize(c.width, c.height)
+		return c, c.confirmation.Init()
+	case dismissConfirmationMsg:
+		c.confirmation = nil
+		return c, nil
+	case preferencesChangedMsg:
+		c.form = nil
+		if msg.next != nil {
+			c.onLoaded = msg.next
 		}
+
+		return c, tea.Sequence(c.list.SetIsLoading(true), c.Reload)
+	case loadedMsg:
+		next := c.onLoaded
+		c.onLoaded = nil
+		return c, next
 	case error:
+		// the action chain stops at the first error
+		c.onLoaded = nil
 		c.err = NewErrorPage(msg)
 		c.err.SetSize(c.width, c.height)
 		return c, c.err.Init()
@@ -433,6 +239,9 @@ func (c *RootList) Update(msg tea.Msg) (Page, tea.Cmd) {
 }
 
 func (c *RootList) View() string {
+	if c.confirmation != nil {
+		return c.confirmation.View()
+	}
 	if c.err != nil {
 		return c.err.View()
 	}
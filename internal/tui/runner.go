@@ -1,27 +1,37 @@
 package tui
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os/exec"
 
 	"github.com/acarl005/stripansi"
-	"github.com/atotto/clipboard"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/muesli/termenv"
+	"github.com/pomdtr/sunbeam/internal/config"
 	"github.com/pomdtr/sunbeam/internal/extensions"
 	"github.com/pomdtr/sunbeam/internal/schemas"
 	"github.com/pomdtr/sunbeam/internal/types"
-	"github.com/pomdtr/sunbeam/internal/utils"
 )
 
 type Runner struct {
 	embed         Page
 	form          *Form
+	confirmation  *Confirmation
 	width, height int
 	cancel        context.CancelFunc
+	loaded        bool
+	renderedQuery string
+	stream        *itemStream
+	// loadID identifies the latest load, the results of the previous ones are ignored
+	loadID int
+	// onLoaded continues the action chain waiting for the output of the command
+	onLoaded tea.Cmd
 
 	extension extensions.Extension
 	command   types.CommandSpec
@@ -70,7 +80,44 @@ func (c *Runner) SetIsLoading(isLoading bool) tea.Cmd {
 
 func (c *Runner) Init() tea.Cmd {
 	termenv.DefaultOutput().SetWindowTitle(fmt.Sprintf("%s - %s", c.command.Title, c.extension.Manifest.Title))
-	return tea.Batch(c.Reload(), c.embed.Init())
+
+	// answers are not persisted if the config cannot be loaded
+	cfg, _ := config.Load(config.Path)
+	if form := NewPreferencesForm(cfg, c.extension, c.input.Preferences, func(preferences map[string]any) tea.Msg {
+		c.form = nil
+		c.input.Preferences = preferences
+		return types.Action{Type: types.ActionTypeReload}
+	}); form != nil {
+		c.form = form
+		c.form.SetSize(c.width, c.height)
+		return tea.Batch(c.embed.Init(), c.form.Init())
+	}
+
+	return tea.Batch(c.load(false), c.embed.Init())
+}
+
+func (c *Runner) actions() actionRunner {
+	return actionRunner{
+		title:       fmt.Sprintf("%s - %s", c.command.Title, c.extension.Manifest.Title),
+		extension:   c.extension,
+		preferences: c.input.Preferences,
+		template:    pageTemplate(c.embed),
+		reload: func(params map[string]any, next tea.Cmd) tea.Cmd {
+			if next != nil {
+				c.onLoaded = next
+			}
+
+			if c.input.Params == nil {
+				c.input.Params = make(map[string]any)
+			}
+
+			for k, v := range params {
+				c.input.Params[k] = v
+			}
+
+			return c.Reload()
+		},
+	}
 }
 
 func (c *Runner) Focus() tea.Cmd {
@@ -82,7 +129,12 @@ func (c *Runner) Focus() tea.Cmd {
 }
 
 func (c *Runner) Blur() tea.Cmd {
-	c.cancel()
+	// the command is not run when its cached output is fresh
+	if c.cancel != nil {
+		c.cancel()
+	}
+	// the chain waiting for the canceled command stops
+	c.onLoaded = nil
 	return nil
 }
 
@@ -94,10 +146,21 @@ func (c *Runner) SetSize(w int, h int) {
 		c.form.SetSize(w, h)
 	}
 
+	if c.confirmation != nil {
+		c.confirmation.SetSize(w, h)
+	}
+
 	c.embed.SetSize(w, h)
 }
 
 func (c *Runner) Update(msg tea.Msg) (Page, tea.Cmd) {
+	// the confirmation captures the keys until it is answered, the other messages keep reaching the page
+	if keyMsg, ok := msg.(tea.KeyMsg); ok && c.confirmation != nil {
+		page, cmd := c.confirmation.Update(keyMsg)
+		c.confirmation = page.(*Confirmation)
+		return c, cmd
+	}
+
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
 		switch msg.String() {
@@ -112,16 +175,24 @@ func (c *Runner) Update(msg tea.Msg) (Page, tea.Cmd) {
 			}
 			return c, PopPageCmd
 		case "ctrl+e":
+			if c.extension.Type == extensions.ExtensionTypeHttp {
+				break
+			}
+
 			editCmd := exec.Command("sunbeam", "edit", c.extension.Entrypoint)
 			return c, tea.ExecProcess(editCmd, func(err error) tea.Msg {
 				if err != nil {
 					return err
 				}
 
-				extension, err := extensions.LoadExtension(c.extension.Entrypoint)
+				// the process of the extension runs the previous version of the sources
+				c.extension.StopProcess()
+				extension, err := extensions.LoadExtension(c.extension.Origin)
 				if err != nil {
 					return err
 				}
+				extension.Alias = c.extension.Alias
+				extension.Config = c.extension.Config
 				c.extension = extension
 
 				return types.Action{
@@ -129,190 +200,101 @@ func (c *Runner) Update(msg tea.Msg) (Page, tea.Cmd) {
 				}
 			})
 		case "ctrl+r":
-			return c, func() tea.Msg {
-				manifest, err := extensions.ExtractManifest(c.extension.Entrypoint)
-				if err != nil {
-					return err
-				}
-				c.extension.Manifest = manifest
-
-				return types.Action{
-					Type: types.ActionTypeReload,
-				}
-			}
+			return c, c.RefreshManifest
 		}
+	case SourceChangedMsg:
+		return c, c.RefreshManifest
 	case Page:
 		c.embed = msg
 		c.embed.SetSize(c.width, c.height)
 		return c, c.embed.Init()
 	case types.Action:
-		switch msg.Type {
-		case types.ActionTypeRun:
-			command, ok := c.extension.Command(msg.Command)
-			if !ok {
-				c.embed = NewErrorPage(fmt.Errorf("command %s not found", msg.Command))
-				c.embed.SetSize(c.width, c.height)
-				return c, c.embed.Init()
-			}
-
-			missing := FindMissingInputs(command.Params, msg.Params)
-			for _, param := range missing {
-				if !param.Required {
-					continue
-				}
-
-				c.form = NewForm(func(values map[string]any) tea.Msg {
-					params := make(map[string]types.Param)
-					for k, v := range msg.Params {
-						params[k] = v
-					}
-
-					for k, v := range values {
-						params[k] = types.Param{
-							Value: v,
-						}
-					}
-
-					return types.Action{
-						Title:   msg.Title,
-						Type:    types.ActionTypeRun,
-						Command: msg.Command,
-						Params:  params,
-						Exit:    msg.Exit,
-						Reload:  msg.Reload,
-					}
-				}, missing...)
-
-				c.form.SetSize(c.width, c.height)
-				return c, tea.Sequence(c.form.Init(), c.form.Focus())
-			}
+		// reloads do not dismiss the preferences form
+		if msg.Type != types.ActionTypeReload {
 			c.form = nil
+		}
 
-			input := types.Payload{
-				Command:     msg.Command,
-				Preferences: c.input.Preferences,
-				Params:      make(map[string]any),
-			}
-
-			for k, v := range msg.Params {
-				input.Params[k] = v.Value
-			}
-
-			switch command.Mode {
-			case types.CommandModeSearch, types.CommandModeFilter, types.CommandModeDetail:
-				runner := NewRunner(c.extension, input)
-
-				return c, PushPageCmd(runner)
-			case types.CommandModeSilent:
-				return c, func() tea.Msg {
-					_, err := c.extension.Output(input)
-
-					if err != nil {
-						return PushPageMsg{NewErrorPage(err)}
-					}
+		return c, c.actions().Run(msg)
+	case dispatchMsg:
+		c.form = nil
+		c.confirmation = nil
+		return c, c.actions().dispatch(msg.action)
+	case showFormMsg:
+		c.form = msg.form
+		c.form.SetSize(c.width, c.height)
+		return c, tea.Sequence(c.form.Init(), c.form.Focus())
+	case showConfirmationMsg:
+		c.confirmation = msg.confirmation
+		c.confirmation.SetSize(c.width, c.height)
+		return c, c.confirmation.Init()
+	case dismissConfirmationMsg:
+		c.confirmation = nil
+		return c, nil
+	case preferencesChangedMsg:
+		c.form = nil
+		if msg.alias == c.extension.Alias {
+			c.input.Preferences = mergePreferences(c.input.Preferences, msg.preferences)
+		}
 
-					if msg.Reload {
-						return types.Action{
-							Type: types.ActionTypeReload,
-						}
-					}
+		if msg.next != nil {
+			c.onLoaded = msg.next
+		}
 
-					if msg.Exit {
-						return ExitMsg{}
-					}
+		return c, c.Reload()
+	case loadedMsg:
+		// streams are loaded once their last item is received
+		if msg.id != c.loadID || c.stream != nil {
+			return c, nil
+		}
 
-					return nil
-				}
-			case types.CommandModeTTY:
-				cmd, err := c.extension.Cmd(input)
-				if err != nil {
-					c.embed = NewErrorPage(err)
-					c.embed.SetSize(c.width, c.height)
-					return c, c.embed.Init()
-				}
+		return c, c.continueChain()
+	case listItemsMsg:
+		if msg.stream != c.stream {
+			return c, nil
+		}
 
-				return c, tea.ExecProcess(cmd, func(err error) tea.Msg {
-					if err != nil {
-						return PushPageMsg{NewErrorPage(err)}
-					}
-
-					if msg.Reload {
-						c.embed.Focus()
-						return types.Action{
-							Type: types.ActionTypeReload,
-						}
-					}
-
-					if msg.Exit {
-						return ExitMsg{}
-					}
-
-					termenv.DefaultOutput().SetWindowTitle(fmt.Sprintf("%s - %s", c.command.Title, c.extension.Manifest.Title))
-					return c.embed.Focus()
-				})
+		if msg.err != nil {
+			c.stream = nil
+			if errors.Is(msg.err, context.Canceled) {
+				return c, nil
 			}
-		case types.ActionTypeEdit:
-			editCmd := exec.Command("sunbeam", "edit", msg.Path)
-			return c, tea.ExecProcess(editCmd, func(err error) tea.Msg {
-				if err != nil {
-					return err
-				}
 
-				if msg.Reload {
-					c.embed.Focus()
-					return c.Reload()
-				}
-
-				if msg.Exit {
-					return ExitMsg{}
-				}
-
-				return c.embed.Focus()
-			})
-		case types.ActionTypeCopy:
-			return c, func() tea.Msg {
-				if err := clipboard.WriteAll(msg.Text); err != nil {
-					return err
-				}
+			c.onLoaded = nil
+			c.embed = NewErrorPage(msg.err)
+			c.embed.SetSize(c.width, c.height)
+			return c, c.embed.Init()
+		}
 
-				if msg.Exit {
-					return ExitMsg{}
-				}
+		list, ok := c.embed.(*List)
+		if !ok {
+			return c, nil
+		}
 
-				return ShowNotificationMsg{"Copied!"}
-			}
-		case types.ActionTypeOpen:
-			return c, func() tea.Msg {
-				if msg.Url != "" {
-					if err := utils.Open(msg.Url); err != nil {
-						return err
-					}
-
-					return ExitMsg{}
-				} else if msg.Path != "" {
-					if err := utils.Open(fmt.Sprintf("file://%s", msg.Path)); err != nil {
-						return err
-					}
-
-					return ExitMsg{}
-				} else {
-					return fmt.Errorf("invalid target")
+		if !msg.stream.started {
+			msg.stream.started = true
+			if c.command.Mode == types.CommandModeSearch && list.OnQueryChange == nil {
+				list.OnQueryChange = func(query string) tea.Cmd {
+					c.input.Query = query
+					return c.load(false)
 				}
 			}
-		case types.ActionTypeReload:
-			if c.input.Params == nil {
-				c.input.Params = make(map[string]any)
-			}
 
-			for k, v := range msg.Params {
-				c.input.Params[k] = v
-			}
+			list.SetItems(msg.items...)
+			list.ResetSelection()
+		} else {
+			list.AddItems(msg.items...)
+		}
 
-			return c, c.Reload()
-		case types.ActionTypeExit:
-			return c, ExitCmd
+		if msg.done {
+			c.stream = nil
+			c.loaded = true
+			return c, tea.Batch(list.SetIsLoading(false), c.continueChain())
 		}
+
+		return c, msg.stream.Next
 	case error:
+		// the action chain stops at the first error
+		c.onLoaded = nil
 		c.embed = NewErrorPage(msg)
 		c.embed.SetSize(c.width, c.height)
 		return c, c.embed.Init()
@@ -330,6 +312,10 @@ func (c *Runner) Update(msg tea.Msg) (Page, tea.Cmd) {
 }
 
 func (c *Runner) View() string {
+	if c.confirmation != nil {
+		return c.confirmation.View()
+	}
+
 	if c.form != nil {
 		return c.form.View()
 	}
@@ -337,97 +323,319 @@ func (c *Runner) View() string {
 	return c.embed.View()
 }
 
+// RefreshManifest extracts the manifest of the extension again, then reloads the page.
+func (c *Runner) RefreshManifest() tea.Msg {
+	// the process of the extension runs the previous version of the sources
+	c.extension.StopProcess()
+
+	var manifest types.Manifest
+	var err error
+	if c.extension.Type == extensions.ExtensionTypeHttp {
+		manifest, err = extensions.FetchManifest(c.extension.Origin)
+	} else if c.extension.Dir != "" {
+		manifest, err = extensions.ExtractManifest(c.extension.Dir)
+	} else {
+		manifest, err = extensions.ExtractManifest(c.extension.Entrypoint)
+	}
+
+	if err != nil {
+		return err
+	}
+	c.extension.Manifest = manifest
+
+	command, ok := c.extension.Command(c.input.Command)
+	if !ok {
+		return fmt.Errorf("command %s not found", c.input.Command)
+	}
+	c.command = command
+
+	return types.Action{
+		Type: types.ActionTypeReload,
+	}
+}
+
+// Reload runs the command again.
+// Commands declaring a cache ttl show their cached output while they run.
 func (c *Runner) Reload() tea.Cmd {
-	return tea.Sequence(c.SetIsLoading(true), func() tea.Msg {
-		if c.cancel != nil {
-			c.cancel()
+	return c.load(true)
+}
+
+// load shows the output of the command.
+// Fresh cached outputs are shown without running the command, unless refresh is set.
+func (c *Runner) load(refresh bool) tea.Cmd {
+	c.loadID++
+	id := c.loadID
+	loaded := func() tea.Msg {
+		return loadedMsg{id: id}
+	}
+
+	if ttl := c.extension.CacheTTL(c.input.Command); ttl > 0 {
+		if output, age, err := c.extension.CachedOutput(c.input); err == nil {
+			if age < ttl && !refresh {
+				return tea.Sequence(func() tea.Msg {
+					return c.render(output, false)
+				}, loaded)
+			}
+
+			return tea.Sequence(c.SetIsLoading(true), func() tea.Msg {
+				return c.render(output, true)
+			}, c.run, loaded)
 		}
+	}
 
-		ctx, cancel := context.WithCancel(context.Background())
-		c.cancel = cancel
-		defer cancel()
+	return tea.Sequence(c.SetIsLoading(true), c.run, loaded)
+}
 
-		cmd, err := c.extension.CmdContext(ctx, c.input)
-		if err != nil {
+// continueChain returns the rest of the action chain waiting for the output of the command.
+func (c *Runner) continueChain() tea.Cmd {
+	next := c.onLoaded
+	c.onLoaded = nil
+	return next
+}
+
+func (c *Runner) run() tea.Msg {
+	if c.cancel != nil {
+		c.cancel()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+
+	if c.command.Streams() && c.extension.Type != extensions.ExtensionTypeHttp {
+		if timeout := c.extension.CommandTimeout(c.input.Command); timeout > 0 {
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+		}
+
+		return c.startStream(ctx, cancel)
+	}
+	defer cancel()
+
+	input := c.input
+	var output []byte
+	var err error
+	if c.loaded {
+		output, err = c.extension.ReloadContext(ctx, input)
+	} else {
+		output, err = c.extension.OutputContext(ctx, input)
+	}
+
+	if err != nil {
+		if errors.Is(ctx.Err(), context.Canceled) {
+			return nil
+		}
+
+		return err
+	}
+	c.loaded = true
+
+	msg := c.render(output, false)
+	if _, ok := msg.(error); !ok {
+		// the cache is only an optimization, failing to write it is not an error
+		_ = c.extension.CacheOutput(input, output)
+	}
+
+	return msg
+}
+
+// render shows the output of the command.
+// If loading is set, the page keeps its loading indicator, as a newer output is on its way.
+func (c *Runner) render(output []byte, loading bool) tea.Msg {
+	switch c.command.Mode {
+	case types.CommandModeDetail:
+		if err := schemas.ValidateDetail(output); err != nil {
 			return err
 		}
 
-		output, err := cmd.Output()
-		if err != nil {
-			if errors.Is(ctx.Err(), context.Canceled) {
-				return nil
+		var detail types.Detail
+		if err := json.Unmarshal(output, &detail); err != nil {
+			return err
+		}
+
+		if detail.Markdown != "" {
+			page := NewDetail(detail.Markdown, detail.Actions...)
+			page.Markdown = true
+			return page
+		}
+
+		page := NewDetail(detail.Text, detail.Actions...)
+		return page
+	case types.CommandModeSearch, types.CommandModeFilter:
+		if err := schemas.ValidateList(output); err != nil {
+			return err
+		}
+
+		var list types.List
+		if err := json.Unmarshal(output, &list); err != nil {
+			return err
+		}
+
+		query := c.input.Query
+		var page *List
+		if embed, ok := c.embed.(*List); ok {
+			page = embed
+			selection, hasSelection := page.Selection()
+
+			page.SetItems(list.Items...)
+			page.SetIsLoading(loading)
+			page.SetEmptyText(list.EmptyText)
+			page.SetActions(list.Actions...)
+			page.SetShowDetail(list.ShowDetail)
+
+			if c.command.Mode == types.CommandModeSearch {
+				page.OnQueryChange = func(query string) tea.Cmd {
+					c.input.Query = query
+					return c.load(false)
+				}
 			}
-			var exitErr *exec.ExitError
-			if errors.As(err, &exitErr) {
-				return fmt.Errorf("command failed: %s", stripansi.Strip(string(exitErr.Stderr)))
+
+			// the same results were refreshed, the user keeps their place in the list
+			if hasSelection && query == c.renderedQuery {
+				page.Select(ListItem(selection).ID())
+			} else if c.command.Mode == types.CommandModeSearch {
+				page.ResetSelection()
 			}
+			c.renderedQuery = query
 
-			return err
+			return nil
 		}
 
-		switch c.command.Mode {
-		case types.CommandModeDetail:
-			if err := schemas.ValidateDetail(output); err != nil {
-				return err
+		page = NewList(list.Items...)
+		page.SetEmptyText(list.EmptyText)
+		page.SetActions(list.Actions...)
+		page.SetShowDetail(list.ShowDetail)
+		if c.command.Mode == types.CommandModeSearch {
+			page.OnQueryChange = func(query string) tea.Cmd {
+				c.input.Query = query
+				return c.load(false)
 			}
+		}
+		c.renderedQuery = query
 
-			var detail types.Detail
-			if err := json.Unmarshal(output, &detail); err != nil {
-				return err
+		return page
+	default:
+		return fmt.Errorf("invalid view type")
+	}
+}
+
+// itemStream reads list items emitted by a command as newline-delimited JSON.
+type itemStream struct {
+	items   chan types.ListItem
+	err     error
+	started bool
+}
+
+type listItemsMsg struct {
+	stream *itemStream
+	items  []types.ListItem
+	done   bool
+	err    error
+}
+
+func (c *Runner) startStream(ctx context.Context, cancel context.CancelFunc) tea.Msg {
+	cmd, err := c.extension.CmdContext(ctx, c.input)
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Start(); err != nil {
+		cancel()
+		return err
+	}
+
+	stream := &itemStream{
+		items: make(chan types.ListItem),
+	}
+	c.stream = stream
+
+	go func() {
+		defer cancel()
+
+		err := readItems(ctx, stdout, stream.items)
+		if err != nil {
+			cancel()
+		}
+
+		if waitErr := cmd.Wait(); err == nil && waitErr != nil {
+			var exitErr *exec.ExitError
+			if errors.As(waitErr, &exitErr) {
+				err = fmt.Errorf("command failed: %s", stripansi.Strip(stderr.String()))
+			} else {
+				err = waitErr
 			}
+		}
 
-			if detail.Markdown != "" {
-				page := NewDetail(detail.Markdown, detail.Actions...)
-				page.Markdown = true
-				return page
+		if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			err = &extensions.TimeoutError{
+				Command: c.input.Command,
+				Timeout: c.extension.CommandTimeout(c.input.Command),
 			}
+		}
 
-			page := NewDetail(detail.Text, detail.Actions...)
-			return page
-		case types.CommandModeSearch, types.CommandModeFilter:
-			if err := schemas.ValidateList(output); err != nil {
+		stream.err = err
+		close(stream.items)
+	}()
+
+	return stream.Next()
+}
+
+func readItems(ctx context.Context, r io.Reader, items chan<- types.ListItem) error {
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if line := bytes.TrimSpace(line); len(line) > 0 {
+			if err := schemas.ValidateListItem(line); err != nil {
 				return err
 			}
 
-			var list types.List
-			if err := json.Unmarshal(output, &list); err != nil {
+			var item types.ListItem
+			if err := json.Unmarshal(line, &item); err != nil {
 				return err
 			}
 
-			var page *List
-			if embed, ok := c.embed.(*List); ok {
-				page = embed
-				page.SetItems(list.Items...)
-				page.SetIsLoading(false)
-				page.SetEmptyText(list.EmptyText)
-				page.SetActions(list.Actions...)
-				page.SetShowDetail(list.ShowDetail)
-
-				if c.command.Mode == types.CommandModeSearch {
-					page.OnQueryChange = func(query string) tea.Cmd {
-						c.input.Query = query
-						return c.Reload()
-					}
-					page.ResetSelection()
-				}
-
-				return nil
+			select {
+			case items <- item:
+			case <-ctx.Done():
+				return ctx.Err()
 			}
+		}
 
-			page = NewList(list.Items...)
-			page.SetEmptyText(list.EmptyText)
-			page.SetActions(list.Actions...)
-			page.SetShowDetail(list.ShowDetail)
-			if c.command.Mode == types.CommandModeSearch {
-				page.OnQueryChange = func(query string) tea.Cmd {
-					c.input.Query = query
-					return c.Reload()
-				}
-			}
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
 
-			return page
+// Next waits for the next items of the stream, and batches the ones that are already available.
+func (s *itemStream) Next() tea.Msg {
+	item, ok := <-s.items
+	if !ok {
+		return listItemsMsg{stream: s, done: true, err: s.err}
+	}
+
+	items := []types.ListItem{item}
+	for len(items) < 100 {
+		select {
+		case item, ok := <-s.items:
+			if !ok {
+				return listItemsMsg{stream: s, items: items, done: true, err: s.err}
+			}
+			items = append(items, item)
 		default:
-			return fmt.Errorf("invalid view type")
+			return listItemsMsg{stream: s, items: items}
 		}
-	})
+	}
+
+	return listItemsMsg{stream: s, items: items}
 }
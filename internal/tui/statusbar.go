@@ -168,6 +168,10 @@ func ActionTitle(action types.Action) string {
 		return "Reload"
 	case types.ActionTypeExec:
 		return "Exec"
+	case types.ActionTypeConfig:
+		return "Configure"
+	case types.ActionTypeHttp:
+		return "Send Request"
 	case types.ActionTypeExit:
 		return "Exit"
 	default:
@@ -0,0 +1,230 @@
+package tui
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/atotto/clipboard"
+	"github.com/pomdtr/sunbeam/internal/types"
+)
+
+var placeholderRegexp = regexp.MustCompile(`{{\s*([a-z.]+)\s*}}`)
+
+// templateContext holds the values of the placeholders of an action, when it is dispatched.
+type templateContext struct {
+	query string
+	item  *types.ListItem
+}
+
+// pageTemplate returns the values of the placeholders of the actions triggered from a page.
+func pageTemplate(page Page) templateContext {
+	list, ok := page.(*List)
+	if !ok {
+		return templateContext{}
+	}
+
+	t := templateContext{query: list.Query()}
+	if item, ok := list.Selection(); ok {
+		t.item = &item
+	}
+
+	return t
+}
+
+// expandAction replaces the placeholders of the command, text, url, body, headers, confirm question and params of an action.
+// Values are quoted for the shell in exec commands, escaped in the path and query of urls, and in json or form bodies.
+func (t templateContext) expandAction(action types.Action) (types.Action, error) {
+	var err error
+	expand := func(text string, escape func(string) string) string {
+		if err != nil {
+			return text
+		}
+
+		var expanded string
+		expanded, err = t.expand(text, escape)
+		return expanded
+	}
+
+	if action.Type == types.ActionTypeExec {
+		action.Command = expand(action.Command, shellQuote)
+	}
+	action.Text = expand(action.Text, nil)
+	if action.Confirm != nil {
+		confirm := *action.Confirm
+		confirm.Question = expand(confirm.Question, nil)
+		action.Confirm = &confirm
+	}
+	action.Body = expand(action.Body, bodyEscape(action))
+	if err == nil {
+		action.Url, err = t.expandUrl(action.Url)
+	}
+
+	if len(action.Headers) > 0 {
+		headers := make(map[string]string)
+		for name, value := range action.Headers {
+			headers[name] = expand(value, nil)
+		}
+		action.Headers = headers
+	}
+
+	if len(action.Params) > 0 {
+		params := make(map[string]types.Param)
+		for name, param := range action.Params {
+			if value, ok := param.Value.(string); ok {
+				param.Value = expand(value, nil)
+			}
+			params[name] = param
+		}
+		action.Params = params
+	}
+
+	return action, err
+}
+
+// expand replaces the placeholders of a text. Unknown placeholders are left untouched.
+func (t templateContext) expand(text string, escape func(string) string) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+
+	var err error
+	expanded := placeholderRegexp.ReplaceAllStringFunc(text, func(match string) string {
+		var value string
+		switch placeholderRegexp.FindStringSubmatch(match)[1] {
+		case "query":
+			value = t.query
+		case "item.title":
+			if t.item != nil {
+				value = t.item.Title
+			}
+		case "item.id":
+			if t.item != nil {
+				value = t.item.Id
+			}
+		case "clipboard":
+			content, clipboardErr := clipboard.ReadAll()
+			if clipboardErr != nil {
+				err = clipboardErr
+				return match
+			}
+			value = content
+		default:
+			return match
+		}
+
+		if escape != nil {
+			return escape(value)
+		}
+
+		return value
+	})
+
+	return expanded, err
+}
+
+// expandUrl replaces the placeholders of an url.
+// Values are escaped as query components after the ? or # of the url, and as path segments after its host.
+// Placeholders preceding the path are replaced as is, so that an url can be built from the clipboard.
+func (t templateContext) expandUrl(text string) (string, error) {
+	queryStart := len(text)
+	if i := strings.IndexAny(text, "?#"); i >= 0 {
+		queryStart = i
+	}
+
+	pathStart := queryStart
+	if i := strings.Index(text[:queryStart], "://"); i >= 0 {
+		if j := strings.Index(text[i+3:queryStart], "/"); j >= 0 {
+			pathStart = i + 3 + j
+		}
+	}
+
+	origin, err := t.expand(text[:pathStart], nil)
+	if err != nil {
+		return text, err
+	}
+
+	path, err := t.expand(text[pathStart:queryStart], url.PathEscape)
+	if err != nil {
+		return text, err
+	}
+
+	query, err := t.expand(text[queryStart:], url.QueryEscape)
+	if err != nil {
+		return text, err
+	}
+
+	return origin + path + query, nil
+}
+
+// bodyEscape returns how values are escaped in the body of an http action, depending on its content type.
+// Bodies are assumed to be json if they are valid json before their placeholders are expanded.
+func bodyEscape(action types.Action) func(string) string {
+	var contentType string
+	for name, value := range action.Headers {
+		if strings.EqualFold(name, "Content-Type") {
+			contentType = value
+		}
+	}
+
+	switch {
+	case strings.Contains(contentType, "json"), contentType == "" && json.Valid([]byte(action.Body)):
+		return jsonEscape
+	case strings.HasPrefix(contentType, "application/x-www-form-urlencoded"):
+		return url.QueryEscape
+	default:
+		return nil
+	}
+}
+
+// jsonEscape escapes a value to be inserted in a json string.
+func jsonEscape(s string) string {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(s); err != nil {
+		return s
+	}
+
+	encoded := strings.TrimSuffix(buf.String(), "\n")
+	return encoded[1 : len(encoded)-1]
+}
+
+// usesQuery reports whether one of the actions refers to the query.
+func usesQuery(actions []types.Action) bool {
+	for _, action := range actions {
+		if action.Type == types.ActionTypeExec && placeholderUses(action.Command, "query") {
+			return true
+		}
+
+		if placeholderUses(action.Text, "query") || placeholderUses(action.Url, "query") || placeholderUses(action.Body, "query") {
+			return true
+		}
+
+		for _, value := range action.Headers {
+			if placeholderUses(value, "query") {
+				return true
+			}
+		}
+
+		for _, param := range action.Params {
+			if value, ok := param.Value.(string); ok && placeholderUses(value, "query") {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func placeholderUses(text string, name string) bool {
+	for _, match := range placeholderRegexp.FindAllStringSubmatch(text, -1) {
+		if match[1] == name {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,137 @@
+package tui
+
+import (
+	"testing"
+
+	"github.com/pomdtr/sunbeam/internal/types"
+)
+
+func TestExpandUrl(t *testing.T) {
+	tc := templateContext{query: "a b/c?d&e=f#g"}
+	for _, c := range []struct {
+		url  string
+		want string
+	}{
+		{url: "https://example.com", want: "https://example.com"},
+		{url: "https://example.com/search?q={{query}}", want: "https://example.com/search?q=a+b%2Fc%3Fd%26e%3Df%23g"},
+		{url: "https://example.com/search#{{ query }}", want: "https://example.com/search#a+b%2Fc%3Fd%26e%3Df%23g"},
+		{url: "https://example.com/repos/{{query}}/issues", want: "https://example.com/repos/a%20b%2Fc%3Fd&e=f%23g/issues"},
+		{url: "https://example.com/{{query}}?q={{query}}", want: "https://example.com/a%20b%2Fc%3Fd&e=f%23g?q=a+b%2Fc%3Fd%26e%3Df%23g"},
+		{url: "https://example.com/{{unknown}}", want: "https://example.com/{{unknown}}"},
+	} {
+		got, err := tc.expandUrl(c.url)
+		if err != nil {
+			t.Errorf("expandUrl(%s): %v", c.url, err)
+			continue
+		}
+
+		if got != c.want {
+			t.Errorf("expandUrl(%s) = %s, want %s", c.url, got, c.want)
+		}
+	}
+
+	// the origin is not escaped, so that an url can be built from a placeholder
+	origin := templateContext{query: "https://example.com"}
+	if got, _ := origin.expandUrl("{{query}}/search?q={{query}}"); got != "https://example.com/search?q=https%3A%2F%2Fexample.com" {
+		t.Errorf("expandUrl of an origin = %s", got)
+	}
+}
+
+func TestBodyEscape(t *testing.T) {
+	const value = `say "hi" & <bye>`
+	for _, tc := range []struct {
+		name    string
+		headers map[string]string
+		body    string
+		want    string
+	}{
+		{name: "json body", body: `{"text": "{{query}}"}`, want: `say \"hi\" & <bye>`},
+		{name: "json content type", headers: map[string]string{"content-type": "application/json"}, body: `text={{query}}`, want: `say \"hi\" & <bye>`},
+		{name: "form", headers: map[string]string{"Content-Type": "application/x-www-form-urlencoded"}, body: `text={{query}}`, want: "say+%22hi%22+%26+%3Cbye%3E"},
+		{name: "text", headers: map[string]string{"Content-Type": "text/plain"}, body: `{{query}}`, want: value},
+		{name: "not json", body: `text: {{query}}`, want: value},
+	} {
+		escape := bodyEscape(types.Action{Headers: tc.headers, Body: tc.body})
+
+		got := value
+		if escape != nil {
+			got = escape(value)
+		}
+
+		if got != tc.want {
+			t.Errorf("%s: escaped %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestJsonEscape(t *testing.T) {
+	for _, tc := range []struct {
+		input string
+		want  string
+	}{
+		{input: "hello", want: "hello"},
+		{input: `"quoted"`, want: `\"quoted\"`},
+		{input: "line\nbreak\ttab", want: `line\nbreak\ttab`},
+		{input: `back\slash`, want: `back\\slash`},
+		{input: "<html> & co", want: "<html> & co"},
+	} {
+		if got := jsonEscape(tc.input); got != tc.want {
+			t.Errorf("jsonEscape(%q) = %s, want %s", tc.input, got, tc.want)
+		}
+	}
+}
+
+func TestExpandAction(t *testing.T) {
+	tc := templateContext{query: "it's $HOME", item: &types.ListItem{Id: "42", Title: "Answer"}}
+
+	action, err := tc.expandAction(types.Action{
+		Type:    types.ActionTypeExec,
+		Command: "echo {{query}} {{item.id}}",
+		Text:    "{{item.title}}: {{query}}",
+		Params:  map[string]types.Param{"title": {Value: "{{item.title}}"}, "count": {Value: 1}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := `echo 'it'\''s $HOME' '42'`; action.Command != want {
+		t.Errorf("command = %s, want %s", action.Command, want)
+	}
+
+	if want := "Answer: it's $HOME"; action.Text != want {
+		t.Errorf("text = %s, want %s", action.Text, want)
+	}
+
+	if action.Params["title"].Value != "Answer" || action.Params["count"].Value != 1 {
+		t.Errorf("params = %+v, want the string params expanded", action.Params)
+	}
+
+	// only the commands of exec actions are quoted
+	action, err = tc.expandAction(types.Action{Type: types.ActionTypeCopy, Command: "{{query}}"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if action.Command != "{{query}}" {
+		t.Errorf("command of a copy action = %s, want it untouched", action.Command)
+	}
+}
+
+func TestUsesQuery(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		action types.Action
+		want   bool
+	}{
+		{name: "exec", action: types.Action{Type: types.ActionTypeExec, Command: "echo {{ query }}"}, want: true},
+		{name: "url", action: types.Action{Type: types.ActionTypeOpen, Url: "https://example.com?q={{query}}"}, want: true},
+		{name: "header", action: types.Action{Type: types.ActionTypeHttp, Headers: map[string]string{"X-Query": "{{query}}"}}, want: true},
+		{name: "param", action: types.Action{Type: types.ActionTypeRun, Params: map[string]types.Param{"q": {Value: "{{query}}"}}}, want: true},
+		{name: "item", action: types.Action{Type: types.ActionTypeCopy, Text: "{{item.title}}"}},
+		{name: "command of a copy action", action: types.Action{Type: types.ActionTypeCopy, Command: "{{query}}"}},
+	} {
+		if got := usesQuery([]types.Action{tc.action}); got != tc.want {
+			t.Errorf("%s: usesQuery = %t, want %t", tc.name, got, tc.want)
+		}
+	}
+}
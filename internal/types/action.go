@@ -24,6 +24,19 @@ type Action struct {
 	Params    map[string]Param `json:"params,omitempty"`
 
 	Dir string `json:"dir,omitempty"`
+
+	Method  string            `json:"method,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body,omitempty"`
+	Output  HttpOutput        `json:"output,omitempty"`
+
+	// Confirm asks the user to confirm the action before it runs.
+	Confirm *Confirm `json:"confirm,omitempty"`
+	// Notification is shown once the action succeeds.
+	Notification string `json:"notification,omitempty"`
+	// Then lists the actions to run, in order, once the action succeeds.
+	// The chain stops at the first action failing.
+	Then []Action `json:"then,omitempty"`
 }
 
 type Param struct {
@@ -79,6 +92,37 @@ func (p Param) MarshalJSON() ([]byte, error) {
 	})
 }
 
+// Confirm is either a boolean, or the question asked before running an action.
+type Confirm struct {
+	Required bool
+	Question string
+}
+
+func (c *Confirm) UnmarshalJSON(bts []byte) error {
+	var b bool
+	if err := json.Unmarshal(bts, &b); err == nil {
+		c.Required = b
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(bts, &s); err == nil {
+		c.Required = true
+		c.Question = s
+		return nil
+	}
+
+	return fmt.Errorf("invalid confirm: %s", string(bts))
+}
+
+func (c Confirm) MarshalJSON() ([]byte, error) {
+	if c.Question != "" {
+		return json.Marshal(c.Question)
+	}
+
+	return json.Marshal(c.Required)
+}
+
 type ActionType string
 
 const (
@@ -90,6 +134,16 @@ const (
 	ActionTypeExec   ActionType = "exec"
 	ActionTypeExit   ActionType = "exit"
 	ActionTypeConfig ActionType = "config"
+	ActionTypeHttp   ActionType = "http"
+)
+
+// HttpOutput is what happens to the response of an http action.
+type HttpOutput string
+
+const (
+	HttpOutputDetail HttpOutput = "detail"
+	HttpOutputCopy   HttpOutput = "copy"
+	HttpOutputNotify HttpOutput = "notify"
 )
 
 type Payload struct {
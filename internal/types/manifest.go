@@ -1,11 +1,20 @@
 package types
 
+import "slices"
+
 type Manifest struct {
-	Title       string        `json:"title"`
-	Description string        `json:"description,omitempty"`
-	Preferences []Input       `json:"preferences,omitempty"`
-	Root        []string      `json:"root"`
-	Commands    []CommandSpec `json:"commands"`
+	Title        string        `json:"title"`
+	Version      string        `json:"version,omitempty"`
+	Description  string        `json:"description,omitempty"`
+	Entrypoint   string        `json:"entrypoint,omitempty"`
+	Persistent   bool          `json:"persistent,omitempty"`
+	Preferences  []Input       `json:"preferences,omitempty"`
+	Requirements []Requirement `json:"requirements,omitempty"`
+	Platforms    []Platfom     `json:"platforms,omitempty"`
+	AllowEnv     []string      `json:"allowEnv,omitempty"`
+	Permissions  *Permissions  `json:"permissions,omitempty"`
+	Root         []string      `json:"root"`
+	Commands     []CommandSpec `json:"commands"`
 }
 
 type RootItem struct {
@@ -15,11 +24,21 @@ type RootItem struct {
 }
 
 type CommandSpec struct {
-	Name   string      `json:"name"`
-	Title  string      `json:"title"`
-	Hidden bool        `json:"hidden,omitempty"`
-	Params []Input     `json:"params,omitempty"`
-	Mode   CommandMode `json:"mode,omitempty"`
+	Name      string      `json:"name"`
+	Title     string      `json:"title"`
+	Hidden    bool        `json:"hidden,omitempty"`
+	Params    []Input     `json:"params,omitempty"`
+	Mode      CommandMode `json:"mode,omitempty"`
+	Stream    bool        `json:"stream,omitempty"`
+	Timeout   int         `json:"timeout,omitempty"`
+	Cache     int         `json:"cache,omitempty"`
+	Platforms []Platfom   `json:"platforms,omitempty"`
+}
+
+// Streams reports whether the command streams its list items.
+// The stream flag only applies to the search and filter modes.
+func (c CommandSpec) Streams() bool {
+	return c.Stream && (c.Mode == CommandModeSearch || c.Mode == CommandModeFilter)
 }
 
 type Platfom string
@@ -34,6 +53,37 @@ type Requirement struct {
 	Link string `json:"link,omitempty"`
 }
 
+// Permissions restrict what an extension is allowed to do.
+// Extensions without permissions are not sandboxed.
+type Permissions struct {
+	Network   bool     `json:"network,omitempty"`
+	Read      []string `json:"read,omitempty"`
+	Write     []string `json:"write,omitempty"`
+	Clipboard bool     `json:"clipboard,omitempty"`
+	Exec      bool     `json:"exec,omitempty"`
+}
+
+// Includes reports whether the permissions grant everything the other permissions request.
+func (p Permissions) Includes(other Permissions) bool {
+	if other.Network && !p.Network || other.Clipboard && !p.Clipboard || other.Exec && !p.Exec {
+		return false
+	}
+
+	for _, path := range other.Read {
+		if !slices.Contains(p.Read, path) && !slices.Contains(p.Write, path) {
+			return false
+		}
+	}
+
+	for _, path := range other.Write {
+		if !slices.Contains(p.Write, path) {
+			return false
+		}
+	}
+
+	return true
+}
+
 type CommandMode string
 
 const (
@@ -0,0 +1,27 @@
+package types
+
+import "testing"
+
+func TestPermissionsIncludes(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		approved  Permissions
+		requested Permissions
+		want      bool
+	}{
+		{name: "empty", want: true},
+		{name: "same flags", approved: Permissions{Network: true, Exec: true}, requested: Permissions{Network: true, Exec: true}, want: true},
+		{name: "fewer flags", approved: Permissions{Network: true, Clipboard: true}, requested: Permissions{Clipboard: true}, want: true},
+		{name: "network", requested: Permissions{Network: true}, want: false},
+		{name: "clipboard", requested: Permissions{Clipboard: true}, want: false},
+		{name: "exec", requested: Permissions{Exec: true}, want: false},
+		{name: "read approved", approved: Permissions{Read: []string{"~/notes"}}, requested: Permissions{Read: []string{"~/notes"}}, want: true},
+		{name: "read granted by write", approved: Permissions{Write: []string{"~/notes"}}, requested: Permissions{Read: []string{"~/notes"}}, want: true},
+		{name: "write not granted by read", approved: Permissions{Read: []string{"~/notes"}}, requested: Permissions{Write: []string{"~/notes"}}, want: false},
+		{name: "other path", approved: Permissions{Write: []string{"~/notes"}}, requested: Permissions{Read: []string{"~/.ssh"}}, want: false},
+	} {
+		if got := tc.approved.Includes(tc.requested); got != tc.want {
+			t.Errorf("%s: Includes = %t, want %t", tc.name, got, tc.want)
+		}
+	}
+}
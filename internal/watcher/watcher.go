@@ -0,0 +1,68 @@
+// Package watcher notifies the changes made to the files of an extension.
+//
+// On Linux, changes are reported by inotify. Other platforms poll the modification times of the files.
+package watcher
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// debounce is the delay without events after which a change is reported,
+// editors usually emit several events when saving a file.
+const debounce = 100 * time.Millisecond
+
+// ignoredDirs are not watched, they are usually large and unrelated to the sources of the extension.
+var ignoredDirs = map[string]bool{
+	".git":         true,
+	"node_modules": true,
+	"__pycache__":  true,
+}
+
+// Watch sends a value on the returned channel each time a file changes under path, until the context is done.
+// The path can be a file or a directory, directories are watched recursively.
+func Watch(ctx context.Context, path string) (<-chan struct{}, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+
+	events, err := watch(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make(chan struct{})
+	go func() {
+		defer close(changes)
+
+		timer := time.NewTimer(debounce)
+		timer.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case _, ok := <-events:
+				if !ok {
+					return
+				}
+				timer.Reset(debounce)
+			case <-timer.C:
+				select {
+				case changes <- struct{}{}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return changes, nil
+}
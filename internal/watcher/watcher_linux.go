@@ -0,0 +1,146 @@
+//go:build linux
+
+package watcher
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const inotifyMask = unix.IN_MODIFY | unix.IN_CLOSE_WRITE | unix.IN_ATTRIB | unix.IN_CREATE | unix.IN_DELETE | unix.IN_MOVED_FROM | unix.IN_MOVED_TO
+
+func watch(ctx context.Context, path string) (<-chan struct{}, error) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize inotify: %w", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		unix.Close(fd)
+		return nil, err
+	}
+
+	// files are replaced on save by some editors, so the parent directory is watched instead
+	var target string
+	dirs := make(map[int]string)
+	if info.IsDir() {
+		if err := addDirs(fd, path, dirs); err != nil {
+			unix.Close(fd)
+			return nil, err
+		}
+	} else {
+		target = filepath.Base(path)
+		wd, err := unix.InotifyAddWatch(fd, filepath.Dir(path), inotifyMask)
+		if err != nil {
+			unix.Close(fd)
+			return nil, fmt.Errorf("failed to watch %s: %w", filepath.Dir(path), err)
+		}
+		dirs[wd] = filepath.Dir(path)
+	}
+
+	events := make(chan struct{})
+	go func() {
+		defer close(events)
+		defer unix.Close(fd)
+
+		buf := make([]byte, 64*(unix.SizeofInotifyEvent+unix.NAME_MAX+1))
+		pollFds := []unix.PollFd{{Fd: int32(fd), Events: unix.POLLIN}}
+		for ctx.Err() == nil {
+			// the timeout lets the loop notice the cancellation of the context
+			n, err := unix.Poll(pollFds, 200)
+			if err == unix.EINTR || n == 0 {
+				continue
+			} else if err != nil {
+				return
+			}
+
+			n, err = unix.Read(fd, buf)
+			if err == unix.EAGAIN || err == unix.EINTR {
+				continue
+			} else if err != nil {
+				return
+			}
+
+			var changed bool
+			for offset := 0; offset+unix.SizeofInotifyEvent <= n; {
+				event := (*unix.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+				nameBytes := buf[offset+unix.SizeofInotifyEvent : offset+unix.SizeofInotifyEvent+int(event.Len)]
+				name := string(nameBytes[:clen(nameBytes)])
+				offset += unix.SizeofInotifyEvent + int(event.Len)
+
+				if target != "" {
+					if name == target {
+						changed = true
+					}
+					continue
+				}
+
+				if ignoredDirs[name] {
+					continue
+				}
+
+				if event.Mask&unix.IN_ISDIR != 0 && event.Mask&(unix.IN_CREATE|unix.IN_MOVED_TO) != 0 {
+					if parent, ok := dirs[int(event.Wd)]; ok {
+						_ = addDirs(fd, filepath.Join(parent, name), dirs)
+					}
+				}
+
+				changed = true
+			}
+
+			if !changed {
+				continue
+			}
+
+			select {
+			case events <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+func addDirs(fd int, root string, dirs map[int]string) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !d.IsDir() {
+			return nil
+		}
+
+		if path != root && ignoredDirs[d.Name()] {
+			return filepath.SkipDir
+		}
+
+		wd, err := unix.InotifyAddWatch(fd, path, inotifyMask)
+		if err != nil {
+			return fmt.Errorf("failed to watch %s: %w", path, err)
+		}
+		dirs[wd] = path
+
+		return nil
+	})
+}
+
+// clen returns the length of a null-terminated name.
+func clen(b []byte) int {
+	for i := 0; i < len(b); i++ {
+		if b[i] == 0 {
+			return i
+		}
+	}
+
+	return len(b)
+}
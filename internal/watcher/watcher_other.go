@@ -0,0 +1,79 @@
+//go:build !linux
+
+package watcher
+
+import (
+	"context"
+	"io/fs"
+	"path/filepath"
+	"time"
+)
+
+const pollInterval = 500 * time.Millisecond
+
+func watch(ctx context.Context, path string) (<-chan struct{}, error) {
+	events := make(chan struct{})
+
+	go func() {
+		defer close(events)
+
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+
+		previous := snapshot(path)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			current := snapshot(path)
+			if current.files == previous.files && current.modTime.Equal(previous.modTime) {
+				continue
+			}
+			previous = current
+
+			select {
+			case events <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+type state struct {
+	files   int
+	modTime time.Time
+}
+
+// snapshot returns the number of files under path, and their latest modification time.
+func snapshot(path string) state {
+	var s state
+	_ = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+
+		if d.IsDir() && p != path && ignoredDirs[d.Name()] {
+			return filepath.SkipDir
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+
+		s.files++
+		if info.ModTime().After(s.modTime) {
+			s.modTime = info.ModTime()
+		}
+
+		return nil
+	})
+
+	return s
+}
@@ -3,6 +3,7 @@
 import * as path from "https://deno.land/std/path/mod.ts";
 const dirname = new URL(".", import.meta.url).pathname;
 const rows = []
+const catalog: { extensions: Record<string, unknown>[] } = { extensions: [] }
 
 rows.push(
     "---",
@@ -29,6 +30,17 @@ for (const entry of entries) {
     } catch (_) {
         console.error(`Failed to parse manifest for ${entry.name}`)
     }
+    const origin = `https://raw.githubusercontent.com/pomdtr/sunbeam/main/extensions/${entry.name}`
+    catalog.extensions.push({
+        name: path.basename(entry.name, path.extname(entry.name)),
+        origin,
+        title: manifest.title,
+        description: manifest.description,
+        version: manifest.version,
+        preferences: manifest.preferences?.map(({ name, title }: { name: string, title: string }) => ({ name, title })),
+        commands: manifest.commands.filter((command: { hidden?: boolean }) => !command.hidden).map(({ name, title }: { name: string, title: string }) => ({ name, title })),
+    })
+
     rows.push(
         "",
         `## [${manifest.title}](https://github.com/pomdtr/sunbeam/tree/main/extensions/${entry.name})`,
@@ -68,9 +80,10 @@ for (const entry of entries) {
         "### Install",
         "",
         "```",
-        `sunbeam extension install https://raw.githubusercontent.com/pomdtr/sunbeam/main/extensions/${entry.name}`,
+        `sunbeam extension install ${origin}`,
         "```"
     )
 }
 
 Deno.writeTextFileSync(path.join(dirname, "..", "www", "frontend", "catalog", "index.md"), rows.join("\n"))
+Deno.writeTextFileSync(path.join(dirname, "..", "www", "frontend", "public", "catalog.json"), JSON.stringify(catalog, null, 2))
@@ -1,6 +1,9 @@
 type ActionProps = {
   title?: string;
   key?: string;
+  confirm?: boolean | string;
+  notification?: string;
+  then?: Action[];
 }
 
 export type CopyAction = {
@@ -22,6 +25,7 @@ export type EditAction = {
 
 export type RunAction = {
   type: "run";
+  extension?: string;
   command: string;
   params?: Record<string, Param>;
   reload?: boolean;
@@ -38,8 +42,32 @@ export type ReloadAction = {
 } & ActionProps;
 
 
+export type ExecAction = {
+  type: "exec";
+  command: string;
+  dir?: string;
+  reload?: boolean;
+  exit?: boolean;
+} & ActionProps;
+
+export type ConfigAction = {
+  type: "config";
+  extension?: string;
+} & ActionProps;
+
+export type HttpAction = {
+  type: "http";
+  url: string;
+  method?: string;
+  headers?: Record<string, string>;
+  body?: string;
+  output?: "detail" | "copy" | "notify";
+  reload?: boolean;
+  exit?: boolean;
+} & ActionProps;
+
 export type ExitAction = {
   type: "exit";
 } & ActionProps;
 
-export type Action = CopyAction | OpenAction | RunAction | ExitAction | EditAction | ReloadAction;
+export type Action = CopyAction | OpenAction | RunAction | ExitAction | EditAction | ReloadAction | ExecAction | ConfigAction | HttpAction;
@@ -1,9 +1,11 @@
 import { Param } from "./action.ts";
+import { Permissions } from "./manifest.ts";
 
 export type Config = {
     $schema?: string;
     oneliners?: Record<string, Oneliner>;
     extensions?: Record<string, ExtensionConfig>;
+    catalog?: string;
 }
 
 export type Oneliner = {
@@ -16,6 +18,11 @@ export type ExtensionConfig = {
     origin: string;
     preferences?: Record<string, string | number | boolean>;
     items?: RootItem[];
+    timeout?: number;
+    env?: Record<string, string>;
+    allowEnv?: string[];
+    denyEnv?: string[];
+    permissions?: Permissions;
 }
 
 export type RootItem = {
@@ -1,16 +1,42 @@
 export type Manifest = {
   title: string;
+  version?: string;
   description?: string;
+  entrypoint?: string;
+  persistent?: boolean;
   root?: string[];
   preferences?: Input[];
+  requirements?: Requirement[];
+  platforms?: Platform[];
+  allowEnv?: string[];
+  permissions?: Permissions;
   commands: CommandSpec[];
 };
 
+export type Platform = "linux" | "macos";
+
+export type Permissions = {
+  network?: boolean;
+  read?: string[];
+  write?: string[];
+  clipboard?: boolean;
+  exec?: boolean;
+};
+
+export type Requirement = {
+  name: string;
+  link?: string;
+};
+
 export type CommandSpec = {
   name: string;
   title: string;
   mode: "search" | "filter" | "detail" | "tty" | "silent";
   hidden?: boolean;
+  stream?: boolean;
+  timeout?: number;
+  cache?: number;
+  platforms?: Platform[];
   description?: string;
   params?: Input[];
 };